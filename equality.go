@@ -0,0 +1,384 @@
+package neldermead
+
+import (
+	"errors"
+	"math"
+	"slices"
+)
+
+// LinearEquality constrains the optimization to the points x that satisfy A·x = B, such as requiring a
+// mixture of fractions to sum to 1 with A = [][]float64{{1, 1, 1}} and B = []float64{1}. The optimization
+// runs in the coordinates of an orthonormal basis of the null space of A, which has one dimension for each
+// degree of freedom left by the equations, and every point passed to the objective function satisfies the
+// equations up to rounding errors. Redundant equations are allowed but the equations must have a solution.
+type LinearEquality struct {
+	// A has one row of coefficients for each equation. Each row must have the same length as x0.
+	A [][]float64
+
+	// B has the right-hand side of each equation.
+	B []float64
+}
+
+func (eq LinearEquality) validate() error {
+	if len(eq.A) != len(eq.B) {
+		return errors.New("invalid Options parameter: LinearEquality must have the same number of rows in A and B")
+	}
+	for i, row := range eq.A {
+		if len(row) != len(eq.A[0]) {
+			return errors.New("invalid Options parameter: LinearEquality rows of A must have the same length")
+		}
+		for _, a := range row {
+			if math.IsNaN(a) || math.IsInf(a, 0) {
+				return errors.New("invalid Options parameter: LinearEquality A must be finite")
+			}
+		}
+		if math.IsNaN(eq.B[i]) || math.IsInf(eq.B[i], 0) {
+			return errors.New("invalid Options parameter: LinearEquality B must be finite")
+		}
+	}
+	return nil
+}
+
+// nullSpace is the search space of the points that satisfy a LinearEquality.
+// A point z maps to particular + basis·z.
+type nullSpace struct {
+	// particular is the solution of the equations with the smallest norm.
+	particular []float64
+
+	// basis is an orthonormal basis of the null space of A.
+	basis [][]float64
+
+	// constraints are the Options.Constraints of the points in the space of
+	// the objective function.
+	constraints []Constraint
+}
+
+// newNullSpace solves the equations, including one equation for each
+// dimension fixed by a Constraint with Min equal to Max.
+func newNullSpace(eq LinearEquality, n int, constraints []Constraint) (nullSpace, error) {
+	var (
+		a = make([][]float64, 0, len(eq.A)+len(constraints))
+		b = slices.Clone(eq.B)
+	)
+	for _, row := range eq.A {
+		a = append(a, slices.Clone(row))
+	}
+	for i, c := range constraints {
+		if c.Min == c.Max {
+			row := make([]float64, n)
+			row[i] = 1
+			a = append(a, row)
+			b = append(b, c.Min)
+		}
+	}
+
+	// Reduce [A|b] to reduced row echelon form with partial pivoting.
+	scale := 0.0
+	for _, row := range a {
+		for _, v := range row {
+			scale = max(scale, math.Abs(v))
+		}
+	}
+	var (
+		tolerance = 1e-12 * max(scale, 1)
+		pivots    []int
+		rank      = 0
+	)
+	for col := 0; col < n && rank < len(a); col++ {
+		best := rank
+		for r := rank + 1; r < len(a); r++ {
+			if math.Abs(a[r][col]) > math.Abs(a[best][col]) {
+				best = r
+			}
+		}
+		if math.Abs(a[best][col]) <= tolerance {
+			continue
+		}
+		a[rank], a[best] = a[best], a[rank]
+		b[rank], b[best] = b[best], b[rank]
+		pivot := a[rank][col]
+		for j := range a[rank] {
+			a[rank][j] /= pivot
+		}
+		b[rank] /= pivot
+		for r := range a {
+			if r == rank || a[r][col] == 0 {
+				continue
+			}
+			factor := a[r][col]
+			for j := range a[r] {
+				a[r][j] -= factor * a[rank][j]
+			}
+			b[r] -= factor * b[rank]
+		}
+		pivots = append(pivots, col)
+		rank++
+	}
+	bScale := 1.0
+	for _, v := range b {
+		bScale = max(bScale, math.Abs(v))
+	}
+	for r := rank; r < len(a); r++ {
+		if math.Abs(b[r]) > 1e-9*bScale {
+			return nullSpace{}, errors.New("invalid Options parameter: LinearEquality must have a solution")
+		}
+	}
+
+	// Each free column gives a vector of the null space, which is then made
+	// orthonormal with the modified Gram-Schmidt process.
+	s := nullSpace{particular: make([]float64, n), constraints: constraints}
+	for r, col := range pivots {
+		s.particular[col] = b[r]
+	}
+	for col := 0; col < n; col++ {
+		if slices.Contains(pivots, col) {
+			continue
+		}
+		v := make([]float64, n)
+		v[col] = 1
+		for r, pivot := range pivots {
+			v[pivot] = -a[r][col]
+		}
+		for range 2 {
+			for _, u := range s.basis {
+				d := dot(u, v)
+				for i := range v {
+					v[i] -= d * u[i]
+				}
+			}
+		}
+		norm := math.Sqrt(dot(v, v))
+		for i := range v {
+			v[i] /= norm
+		}
+		s.basis = append(s.basis, v)
+	}
+	// Removing the components along the null space leaves the solution with
+	// the smallest norm.
+	for _, u := range s.basis {
+		d := dot(u, s.particular)
+		for i := range s.particular {
+			s.particular[i] -= d * u[i]
+		}
+	}
+	return s, nil
+}
+
+func dot(u, v []float64) float64 {
+	sum := 0.0
+	for i := range u {
+		sum += u[i] * v[i]
+	}
+	return sum
+}
+
+// coordinates returns the null space coordinates of x, whose projection
+// onto the solutions of the equations is particular + basis·z.
+func (s nullSpace) coordinates(x []float64) []float64 {
+	z := make([]float64, len(s.basis))
+	for k, u := range s.basis {
+		for i := range x {
+			z[k] += u[i] * (x[i] - s.particular[i])
+		}
+	}
+	return z
+}
+
+// project returns the point closest to x that satisfies the equations.
+func (s nullSpace) project(x []float64) []float64 {
+	return s.userSpace()(s.coordinates(x))
+}
+
+// boundTolerance is how far, relative to the magnitude of the terms that are
+// summed to map a coordinate, the coordinate may be outside a bound and still
+// be moved onto it. It covers the rounding errors of mapping a point that is
+// on a bound, such as a corner of the simplex of mixture fractions, to the
+// null space and back.
+const boundTolerance = 1e-13
+
+// userSpace returns the mapping from the null space coordinates to the
+// space of the objective function. Fixed dimensions are set exactly to
+// their value and coordinates that are outside a bound by no more than
+// rounding errors are moved onto it.
+func (s nullSpace) userSpace() userSpace {
+	return func(z []float64) []float64 {
+		var (
+			x         = slices.Clone(s.particular)
+			magnitude = make([]float64, len(x))
+		)
+		for i := range x {
+			magnitude[i] = math.Abs(x[i])
+		}
+		for k, u := range s.basis {
+			for i := range x {
+				x[i] += z[k] * u[i]
+				magnitude[i] += math.Abs(z[k] * u[i])
+			}
+		}
+		for i, c := range s.constraints {
+			tolerance := boundTolerance * magnitude[i]
+			switch {
+			case c.Min == c.Max:
+				x[i] = c.Min
+			case x[i] < c.Min && x[i] >= c.Min-tolerance:
+				x[i] = c.Min
+			case x[i] > c.Max && x[i] <= c.Max+tolerance:
+				x[i] = c.Max
+			}
+		}
+		return x
+	}
+}
+
+// feasible reports whether x satisfies the constraints.
+func (s nullSpace) feasible(x []float64) bool {
+	for i, c := range s.constraints {
+		if !(x[i] >= c.Min && x[i] <= c.Max) {
+			return false
+		}
+	}
+	return true
+}
+
+// interior reports whether x is strictly inside the bounds of every
+// dimension that is not fixed.
+func (s nullSpace) interior(x []float64) bool {
+	for i, c := range s.constraints {
+		if c.Min != c.Max && !(x[i] > c.Min && x[i] < c.Max) {
+			return false
+		}
+	}
+	return true
+}
+
+// barrier returns the objective function with every point outside the
+// constraints rejected without calling f, so it is set to +Inf and not
+// counted as an evaluation.
+func (s nullSpace) barrier(f ObjectiveE) ObjectiveE {
+	if len(s.constraints) == 0 {
+		return f
+	}
+	return func(x []float64) (float64, error) {
+		if !s.feasible(x) {
+			return 0, errInfeasible
+		}
+		return f(x)
+	}
+}
+
+// internal returns the starting point and options of the optimization in
+// the null space coordinates.
+func (s nullSpace) internal(x0 []float64, options Options) ([]float64, Options, error) {
+	if !s.feasible(s.project(x0)) {
+		return nil, options, errors.New("invalid initial x parameter: the projection of x0 onto the solutions of LinearEquality must satisfy the constraints")
+	}
+	options.LinearEquality = LinearEquality{}
+	options.Constraints = nil
+	options.Transform = TransformNone
+	if len(s.constraints) > 0 {
+		options.InitialSimplex = s.feasibleSimplex(options.InitialSimplex)
+	}
+	return s.coordinates(x0), options, nil
+}
+
+// maxMoves limits how many times feasibleSimplex halves the step of a vertex
+// or moves it towards the feasible vertices.
+const maxMoves = 16
+
+// feasibleSimplex wraps initialSimplex to move the vertices that are outside
+// the constraints inside when it can. The barrier gives every such vertex the
+// value +Inf, and when x0 is on a bound, such as a corner of the simplex of
+// mixture fractions, shrinking never brings a vertex that starts in an
+// infeasible direction back inside. The step from x0 to a vertex outside is
+// halved, in both directions, until it is inside. A vertex that is still
+// outside is moved towards the mean of the feasible vertices, which is inside
+// along with every point between it and x0. Finally, the vertices other than
+// x0 that are on a bound are moved away from it, because a simplex with a
+// face on a bound reflects every point out of the constraints and shrinks.
+func (s nullSpace) feasibleSimplex(initialSimplex InitialSimplex) InitialSimplex {
+	if initialSimplex == nil {
+		initialSimplex = StepSimplex(DefaultStep)
+	}
+	feasible := func(z []float64) bool {
+		return s.feasible(s.userSpace()(z))
+	}
+	return initialSimplexFunc(func(z0 []float64, constraints []Constraint) (Simplex, error) {
+		simplex, err := initialSimplex.initialSimplex(z0, constraints)
+		if err != nil {
+			return simplex, err
+		}
+		var (
+			mean      = make([]float64, len(z0))
+			count     = 0
+			remaining []int
+			step      = make([]float64, len(z0))
+		)
+		for i, p := range simplex.Points {
+			for j := range step {
+				step[j] = p.X[j] - z0[j]
+			}
+			for k := 0; k < maxMoves && !feasible(p.X); k++ {
+				// Try the mirrored step before the halved one.
+				if k%2 == 0 {
+					for j := range step {
+						p.X[j] = z0[j] - step[j]
+					}
+					continue
+				}
+				for j := range step {
+					step[j] /= 2
+					p.X[j] = z0[j] + step[j]
+				}
+			}
+			if !feasible(p.X) {
+				remaining = append(remaining, i)
+				continue
+			}
+			for j := range p.X {
+				mean[j] += p.X[j]
+			}
+			count++
+		}
+		if count == 0 {
+			return simplex, nil
+		}
+		for j := range mean {
+			mean[j] /= float64(count)
+		}
+		for _, i := range remaining {
+			x := simplex.Points[i].X
+			for k := 0; k < maxMoves && !feasible(x); k++ {
+				for j := range x {
+					x[j] = (x[j] + mean[j]) / 2
+				}
+			}
+		}
+
+		// The other vertices that are on a bound are moved halfway towards
+		// the mean of the vertices strictly inside the bounds.
+		setZero(mean)
+		count = 0
+		for _, p := range simplex.Points {
+			if s.interior(s.userSpace()(p.X)) {
+				for j := range p.X {
+					mean[j] += p.X[j]
+				}
+				count++
+			}
+		}
+		if count == 0 {
+			return simplex, nil
+		}
+		for j := range mean {
+			mean[j] /= float64(count)
+		}
+		for _, p := range simplex.Points[1:] {
+			if x := s.userSpace()(p.X); s.feasible(x) && !s.interior(x) {
+				for j := range p.X {
+					p.X[j] = (p.X[j] + mean[j]) / 2
+				}
+			}
+		}
+		return simplex, nil
+	})
+}
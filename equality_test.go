@@ -0,0 +1,246 @@
+package neldermead
+
+import (
+	"math"
+	"testing"
+)
+
+func TestNullSpace(t *testing.T) {
+	for _, tt := range []struct {
+		name        string
+		eq          LinearEquality
+		n           int
+		constraints []Constraint
+		dimensions  int
+	}{
+		{name: "sum", eq: LinearEquality{A: [][]float64{{1, 1, 1}}, B: []float64{1}}, n: 3, dimensions: 2},
+		{name: "two equations", eq: LinearEquality{A: [][]float64{{1, 2, 0, -1}, {0, 1, 3, 1}}, B: []float64{4, -2}}, n: 4, dimensions: 2},
+		{name: "redundant", eq: LinearEquality{A: [][]float64{{1, 1, 0}, {2, 2, 0}, {0, 0, 1}}, B: []float64{1, 2, 5}}, n: 3, dimensions: 1},
+		{name: "small pivot", eq: LinearEquality{A: [][]float64{{1e-8, 1, 0}, {1, 1, 1}}, B: []float64{1, 0}}, n: 3, dimensions: 1},
+		{name: "determined", eq: LinearEquality{A: [][]float64{{2, 0}, {0, 4}}, B: []float64{1, 1}}, n: 2, dimensions: 0},
+		{
+			name:        "fixed dimension",
+			eq:          LinearEquality{A: [][]float64{{1, 1, 1}}, B: []float64{1}},
+			n:           3,
+			constraints: []Constraint{{Min: 0, Max: 1}, {Min: 0.25, Max: 0.25}, {Min: 0, Max: 1}},
+			dimensions:  1,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := newNullSpace(tt.eq, tt.n, tt.constraints)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(s.basis) != tt.dimensions {
+				t.Fatalf("expected %d dimensions got %d", tt.dimensions, len(s.basis))
+			}
+			for i, u := range s.basis {
+				for j, v := range s.basis {
+					exp := 0.0
+					if i == j {
+						exp = 1
+					}
+					if d := dot(u, v); math.Abs(d-exp) > 1e-12 {
+						t.Errorf("expected the basis to be orthonormal got %v for vectors %d and %d", d, i, j)
+					}
+				}
+				for r, row := range tt.eq.A {
+					if d := dot(row, u); math.Abs(d) > 1e-12 {
+						t.Errorf("expected basis vector %d to be in the null space of row %d got %v", i, r, d)
+					}
+				}
+				if d := dot(u, s.particular); math.Abs(d) > 1e-12 {
+					t.Errorf("expected the particular solution to have the smallest norm")
+				}
+			}
+			for _, z := range [][]float64{make([]float64, tt.dimensions), {3, -7}, {-1e3, 0.5}} {
+				x := s.userSpace()(z[:tt.dimensions])
+				for r, row := range tt.eq.A {
+					if d := dot(row, x) - tt.eq.B[r]; math.Abs(d) > 1e-9 {
+						t.Errorf("expected %v to satisfy equation %d got a residual of %v", x, r, d)
+					}
+				}
+				for i, c := range tt.constraints {
+					if c.Min == c.Max && x[i] != c.Min {
+						t.Errorf("expected dimension %d of %v to be fixed to %v", i, x, c.Min)
+					}
+				}
+			}
+		})
+	}
+
+	t.Run("no solution", func(t *testing.T) {
+		for _, tt := range []struct {
+			name        string
+			eq          LinearEquality
+			constraints []Constraint
+		}{
+			{name: "inconsistent", eq: LinearEquality{A: [][]float64{{1, 1}, {2, 2}}, B: []float64{1, 3}}},
+			{
+				name:        "fixed dimensions",
+				eq:          LinearEquality{A: [][]float64{{1, 1}}, B: []float64{1}},
+				constraints: []Constraint{{Min: 1, Max: 1}, {Min: 1, Max: 1}},
+			},
+		} {
+			if _, err := newNullSpace(tt.eq, 2, tt.constraints); err == nil {
+				t.Errorf("%s: expected an error", tt.name)
+			}
+		}
+	})
+}
+
+func TestOptions_LinearEquality(t *testing.T) {
+	sum := LinearEquality{A: [][]float64{{1, 1, 1}}, B: []float64{1}}
+	// The unconstrained minimum at (0.5, 0.5, 0.5) does not sum to 1, so the
+	// minimum is its projection onto the plane.
+	objective := func(x []float64) float64 {
+		return math.Pow(x[0]-0.5, 2) + math.Pow(x[1]-0.5, 2) + math.Pow(x[2]-0.5, 2)
+	}
+	requireSum := func(t *testing.T, f Objective) Objective {
+		t.Helper()
+		return func(x []float64) float64 {
+			if len(x) != 3 || math.Abs(x[0]+x[1]+x[2]-1) > 1e-12 {
+				t.Errorf("expected the objective function to be called with a point that sums to 1 got %v", x)
+			}
+			return f(x)
+		}
+	}
+
+	t.Run("projection", func(t *testing.T) {
+		options := NewOptions()
+		options.Tolerance = 1e-12
+		options.LinearEquality = sum
+		var observed int
+		options.Observer = func(info IterationInfo) bool {
+			observed++
+			if len(info.Best.X) != 3 {
+				t.Errorf("expected the observer to receive every dimension got %v", info.Best.X)
+			}
+			return false
+		}
+
+		result, err := RunWithResult(requireSum(t, objective), []float64{0.9, 0.2, 0.4}, options)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		expectPoint(t, Point{X: []float64{1.0 / 3, 1.0 / 3, 1.0 / 3}, F: 1.0 / 12}, result.Point, 5)
+		if len(result.Simplex.Points) != 3 {
+			t.Errorf("expected a simplex with 3 vertices for 2 degrees of freedom got %d", len(result.Simplex.Points))
+		}
+		if observed == 0 {
+			t.Errorf("expected the observer to be called")
+		}
+	})
+
+	t.Run("mixture fractions", func(t *testing.T) {
+		constraints := []Constraint{{Min: 0, Max: 1}, {Min: 0, Max: 1}, {Min: 0, Max: 1}}
+		options := NewOptions()
+		options.Tolerance = 1e-12
+		options.LinearEquality = sum
+		options.Constraints = constraints
+
+		var calls int
+		f := requireSum(t, feasibleObjective(t, constraints, func(x []float64) float64 {
+			calls++
+			return math.Pow(x[0]-0.8, 2) + math.Pow(x[1]-0.6, 2) + math.Pow(x[2]+0.4, 2)
+		}))
+		// The corners and edges of the simplex of fractions are on the
+		// bounds, which rounding errors must not push x0 off of.
+		for _, x0 := range [][]float64{
+			{1.0 / 3, 1.0 / 3, 1.0 / 3},
+			{1, 0, 0},
+			{0, 1, 0},
+			{0, 0.5, 0.5},
+			{0.5, 0, 0.5},
+		} {
+			calls = 0
+			result, err := RunWithResult(f, x0, options)
+			if err != nil {
+				t.Fatalf("x0 = %v: unexpected error: %v", x0, err)
+			}
+			expectPoint(t, Point{X: []float64{0.6, 0.4, 0}, F: 0.24}, result.Point, 3)
+			if result.Evaluations != calls {
+				t.Errorf("x0 = %v: expected only the %d objective function calls to be counted got %d", x0, calls, result.Evaluations)
+			}
+		}
+	})
+
+	t.Run("x0 is projected", func(t *testing.T) {
+		options := NewOptions()
+		options.LinearEquality = sum
+		options.MaxEvaluations = 1
+		var first []float64
+		_, err := RunWithResult(func(x []float64) float64 {
+			first = x
+			return objective(x)
+		}, []float64{1, 1, 1}, options)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		expectPoint(t, Point{X: []float64{1.0 / 3, 1.0 / 3, 1.0 / 3}}, Point{X: first}, 12)
+	})
+
+	t.Run("determined", func(t *testing.T) {
+		options := NewOptions()
+		options.LinearEquality = LinearEquality{A: [][]float64{{1, 0}, {0, 1}}, B: []float64{2, 3}}
+		result, err := RunWithResult(func(x []float64) float64 { return x[0] * x[1] }, []float64{0, 0}, options)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		expectPoint(t, Point{X: []float64{2, 3}, F: 6}, result.Point, 12)
+		if result.EdgeLength != 0 {
+			t.Errorf("expected an edge length of 0 got %g", result.EdgeLength)
+		}
+	})
+
+	t.Run("parallel vertices", func(t *testing.T) {
+		options := NewOptions()
+		options.Tolerance = 1e-12
+		options.LinearEquality = LinearEquality{A: [][]float64{{1, 1, 1, 1, 1, 1}}, B: []float64{1}}
+		objective := func(x []float64) float64 {
+			sum := 0.0
+			for _, xi := range x {
+				sum += (xi - 0.5) * (xi - 0.5)
+			}
+			return sum
+		}
+		x0 := []float64{0.9, 0.2, 0.4, 0, 0, 0}
+
+		point, err := RunParallel(objective, x0, 2, options)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		x := 1.0 / 6
+		expectPoint(t, Point{X: []float64{x, x, x, x, x, x}, F: 6 * (x - 0.5) * (x - 0.5)}, point, 3)
+
+		// Moving 6 vertices is limited to the 5 degrees of freedom.
+		if _, err := RunParallel(objective, x0, 6, options); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		for _, tt := range []struct {
+			name        string
+			eq          LinearEquality
+			constraints []Constraint
+			x0          []float64
+		}{
+			{name: "wrong length", eq: LinearEquality{A: [][]float64{{1, 1}}, B: []float64{1}}, x0: []float64{0, 0, 0}},
+			{name: "no solution", eq: LinearEquality{A: [][]float64{{1, 1, 1}, {1, 1, 1}}, B: []float64{1, 2}}, x0: []float64{0, 0, 0}},
+			{
+				name:        "projected x0 outside constraints",
+				eq:          sum,
+				constraints: []Constraint{{Min: 0, Max: 1}, {Min: 0, Max: 1}, {Min: 0, Max: 1}},
+				x0:          []float64{1, 0, 0.9},
+			},
+		} {
+			options := NewOptions()
+			options.LinearEquality = tt.eq
+			options.Constraints = tt.constraints
+			if _, err := Run(objective, tt.x0, options); err == nil {
+				t.Errorf("%s: expected an error", tt.name)
+			}
+		}
+	})
+}
@@ -0,0 +1,192 @@
+package neldermead
+
+import (
+	"context"
+	"errors"
+	"math"
+	"slices"
+	"time"
+)
+
+const (
+	// DefaultPenaltyWeight is the weight of the squared constraint violations added to the objective function
+	// by InequalityPenalty and the initial weight used by InequalityAugmentedLagrangian.
+	DefaultPenaltyWeight = 1e3
+
+	// DefaultConstraintTolerance is the largest constraint violation InequalityAugmentedLagrangian accepts.
+	DefaultConstraintTolerance = 1e-6
+)
+
+// maxOuterIterations bounds the number of times InequalityAugmentedLagrangian
+// updates the multipliers and optimizes again.
+const maxOuterIterations = 20
+
+// InequalityHandling selects how Options.InequalityConstraints are enforced.
+type InequalityHandling int
+
+const (
+	// InequalityPenalty minimizes f(x) + PenaltyWeight * sum(max(0, g(x))^2). The result may violate the
+	// constraints slightly. A larger PenaltyWeight reduces the violation but makes the problem harder to solve.
+	InequalityPenalty InequalityHandling = iota
+
+	// InequalityAugmentedLagrangian runs an outer loop of penalized optimizations, each starting from the
+	// result of the previous one. After each optimization, the Lagrange multiplier estimates are updated and
+	// the penalty weight is increased when the violation did not decrease enough. The loop stops once the
+	// largest violation is at most ConstraintTolerance. It reaches the constraints without a large penalty
+	// weight at the cost of more evaluations.
+	InequalityAugmentedLagrangian
+
+	// InequalityBarrier treats every point that violates a constraint as +Inf, the extreme barrier of Audet
+	// and Dennis. The objective function is not called at those points and they do not count as evaluations
+	// towards Result.Evaluations or MaxEvaluations. x0 must satisfy the constraints.
+	InequalityBarrier
+)
+
+// inequality enforces Options.InequalityConstraints by optimizing a merit
+// function in place of the objective function.
+type inequality struct {
+	constraints []func(x []float64) float64
+}
+
+// violation returns the largest amount by which x violates a constraint.
+// A constraint that returns NaN is violated by +Inf.
+func (c inequality) violation(x []float64) float64 {
+	v := 0.0
+	for _, g := range c.constraints {
+		gx := g(x)
+		if math.IsNaN(gx) {
+			return math.Inf(1)
+		}
+		v = max(v, gx)
+	}
+	return v
+}
+
+// penalty returns the objective function plus weight times the sum of the
+// squared constraint violations.
+func (c inequality) penalty(f ObjectiveE, weight float64) ObjectiveE {
+	return c.merit(f, func(j int, gx float64) float64 {
+		v := max(gx, 0)
+		return weight * v * v
+	})
+}
+
+// lagrangian returns the augmented Lagrangian of the objective function for
+// the given multiplier estimates, as defined by Rockafellar for inequality
+// constraints.
+func (c inequality) lagrangian(f ObjectiveE, multipliers []float64, weight float64) ObjectiveE {
+	return c.merit(f, func(j int, gx float64) float64 {
+		shift := multipliers[j] / weight
+		v := max(gx+shift, 0)
+		return weight / 2 * (v*v - shift*shift)
+	})
+}
+
+// merit returns the objective function plus the sum of term over the
+// constraint values. A point where a constraint returns NaN is rejected
+// without calling f, like the infeasible points of barrier, because the
+// term would make the merit function NaN.
+func (c inequality) merit(f ObjectiveE, term func(j int, gx float64) float64) ObjectiveE {
+	return func(x []float64) (float64, error) {
+		penalty := 0.0
+		for j, g := range c.constraints {
+			gx := g(x)
+			if math.IsNaN(gx) {
+				return 0, errInfeasible
+			}
+			penalty += term(j, gx)
+		}
+		y, err := f(x)
+		if err != nil {
+			return y, err
+		}
+		return y + penalty, nil
+	}
+}
+
+// barrier returns the objective function with every infeasible point
+// rejected without calling f, so it is set to +Inf and not counted as an
+// evaluation.
+func (c inequality) barrier(f ObjectiveE) ObjectiveE {
+	return func(x []float64) (float64, error) {
+		if c.violation(x) > 0 {
+			return 0, errInfeasible
+		}
+		return f(x)
+	}
+}
+
+// runInequality runs an optimization enforcing Options.InequalityConstraints
+// as configured by Options.InequalityHandling.
+func runInequality(ctx context.Context, f ObjectiveE, x0 []float64, parallelVertices int, options Options) (Result, error) {
+	c := inequality{constraints: options.InequalityConstraints}
+	options.InequalityConstraints = nil
+	switch options.InequalityHandling {
+	case InequalityBarrier:
+		if c.violation(x0) > 0 {
+			return Result{}, errors.New("invalid initial x parameter: x0 must satisfy the InequalityConstraints when InequalityHandling is InequalityBarrier")
+		}
+		return runE(ctx, c.barrier(f), x0, parallelVertices, options)
+	case InequalityPenalty:
+		result, err := runE(ctx, c.penalty(f, options.PenaltyWeight), x0, parallelVertices, options)
+		if len(result.X) > 0 {
+			result.ConstraintViolation = c.violation(result.X)
+		}
+		return result, err
+	}
+
+	var (
+		multipliers   = make([]float64, len(c.constraints))
+		weight        = options.PenaltyWeight
+		lastViolation = math.Inf(1)
+		total         Result
+		now           = options.Now
+		x             = x0
+	)
+	if now == nil {
+		now = time.Now
+	}
+	start := now()
+	for outer := 0; ; outer++ {
+		inner := options
+		inner.MaxIterations -= total.Iterations
+		if options.MaxEvaluations > 0 {
+			inner.MaxEvaluations -= total.Evaluations
+		}
+		if options.MaxDuration > 0 {
+			inner.MaxDuration -= now().Sub(start)
+		}
+		if observer, completed := options.Observer, total.Iterations; observer != nil {
+			inner.Observer = func(info IterationInfo) bool {
+				info.Iteration += completed
+				return observer(info)
+			}
+		}
+
+		result, err := runE(ctx, c.lagrangian(f, slices.Clone(multipliers), weight), x, parallelVertices, inner)
+		result.Iterations += total.Iterations
+		result.Evaluations += total.Evaluations
+		result.Restarts += total.Restarts
+		if len(result.X) == 0 {
+			return result, err
+		}
+		violation := c.violation(result.X)
+		result.ConstraintViolation = violation
+		total = result
+		if err != nil || result.Termination != TerminationConverged || violation <= options.ConstraintTolerance || outer+1 == maxOuterIterations ||
+			result.Iterations >= options.MaxIterations ||
+			options.MaxEvaluations > 0 && result.Evaluations >= options.MaxEvaluations ||
+			options.MaxDuration > 0 && now().Sub(start) >= options.MaxDuration {
+			return result, err
+		}
+
+		for j, g := range c.constraints {
+			multipliers[j] = max(0, multipliers[j]+weight*g(result.X))
+		}
+		if violation > lastViolation/4 {
+			weight *= 10
+		}
+		lastViolation = violation
+		x = slices.Clone(result.X)
+	}
+}
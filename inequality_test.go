@@ -0,0 +1,205 @@
+package neldermead
+
+import (
+	"context"
+	"math"
+	"testing"
+)
+
+func TestOptions_InequalityConstraints(t *testing.T) {
+	// The unconstrained minimum at (1, 1) violates x[0] + x[1] <= 1, so the
+	// constrained minimum is on the line at (0.5, 0.5).
+	objective := func(x []float64) float64 {
+		return math.Pow(x[0]-1, 2) + math.Pow(x[1]-1, 2)
+	}
+	sum := func(x []float64) float64 {
+		return x[0] + x[1] - 1
+	}
+
+	t.Run("penalty", func(t *testing.T) {
+		options := NewOptions()
+		options.Tolerance = 1e-12
+		options.InequalityConstraints = []func([]float64) float64{sum}
+
+		result, err := RunWithResult(objective, []float64{0, 0}, options)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		expectPoint(t, Point{X: []float64{0.5, 0.5}, F: 0.5}, result.Point, 3)
+		// The static penalty stops short of the constraint by about 1/(2*PenaltyWeight).
+		if result.ConstraintViolation <= 0 || result.ConstraintViolation > 1e-3 {
+			t.Errorf("expected a small constraint violation got %v", result.ConstraintViolation)
+		}
+		if exp := sum(result.X); result.ConstraintViolation != exp {
+			t.Errorf("expected the constraint violation to be %v got %v", exp, result.ConstraintViolation)
+		}
+	})
+
+	t.Run("augmented lagrangian", func(t *testing.T) {
+		options := NewOptions()
+		options.Tolerance = 1e-12
+		options.InequalityConstraints = []func([]float64) float64{sum}
+		options.InequalityHandling = InequalityAugmentedLagrangian
+		options.PenaltyWeight = 100
+		options.MaxIterations = 10_000
+		var iterations []int
+		options.Observer = func(info IterationInfo) bool {
+			iterations = append(iterations, info.Iteration)
+			return false
+		}
+
+		result, err := RunWithResult(objective, []float64{0, 0}, options)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		expectPoint(t, Point{X: []float64{0.5, 0.5}, F: 0.5}, result.Point, 5)
+		if result.ConstraintViolation > options.ConstraintTolerance {
+			t.Errorf("expected the constraint violation to be at most %v got %v", options.ConstraintTolerance, result.ConstraintViolation)
+		}
+		if result.Termination != TerminationConverged {
+			t.Errorf("expected %s got %s", TerminationConverged, result.Termination)
+		}
+		if len(iterations) != result.Iterations {
+			t.Errorf("expected %d observed iterations got %d", result.Iterations, len(iterations))
+		}
+		for i, iteration := range iterations {
+			if iteration != i {
+				t.Fatalf("expected the iterations to be numbered across the outer loop got %d at %d", iteration, i)
+			}
+		}
+	})
+
+	t.Run("augmented lagrangian respects MaxEvaluations", func(t *testing.T) {
+		options := NewOptions()
+		options.Tolerance = 1e-12
+		options.InequalityConstraints = []func([]float64) float64{sum}
+		options.InequalityHandling = InequalityAugmentedLagrangian
+		options.PenaltyWeight = 1
+		options.MaxEvaluations = 150
+
+		result, err := RunWithResult(objective, []float64{0, 0}, options)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if result.Evaluations > options.MaxEvaluations {
+			t.Errorf("expected at most %d evaluations got %d", options.MaxEvaluations, result.Evaluations)
+		}
+	})
+
+	t.Run("barrier", func(t *testing.T) {
+		options := NewOptions()
+		options.Tolerance = 1e-12
+		options.InequalityConstraints = []func([]float64) float64{sum}
+		options.InequalityHandling = InequalityBarrier
+
+		var calls int
+		result, err := RunWithResult(func(x []float64) float64 {
+			calls++
+			if sum(x) > 0 {
+				t.Errorf("expected the objective function not to be called at the infeasible point %v", x)
+			}
+			return objective(x)
+		}, []float64{0, 0}, options)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		expectPoint(t, Point{X: []float64{0.5, 0.5}, F: 0.5}, result.Point, 2)
+		if result.ConstraintViolation != 0 {
+			t.Errorf("expected no constraint violation got %v", result.ConstraintViolation)
+		}
+		if result.Evaluations != calls {
+			t.Errorf("expected only the %d objective function calls to be counted got %d", calls, result.Evaluations)
+		}
+	})
+
+	t.Run("barrier does not spend MaxEvaluations on infeasible points", func(t *testing.T) {
+		options := NewOptions()
+		options.Tolerance = 1e-12
+		options.InequalityConstraints = []func([]float64) float64{sum}
+		options.InequalityHandling = InequalityBarrier
+		options.MaxEvaluations = 40
+
+		var calls int
+		result, err := RunWithResult(func(x []float64) float64 {
+			calls++
+			return objective(x)
+		}, []float64{0, 0}, options)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if calls != options.MaxEvaluations || result.Evaluations != calls {
+			t.Errorf("expected %d objective function calls and evaluations got %d and %d", options.MaxEvaluations, calls, result.Evaluations)
+		}
+	})
+
+	t.Run("barrier requires a feasible x0", func(t *testing.T) {
+		options := NewOptions()
+		options.InequalityConstraints = []func([]float64) float64{sum}
+		options.InequalityHandling = InequalityBarrier
+
+		if _, err := Run(objective, []float64{1, 1}, options); err == nil {
+			t.Errorf("expected an error")
+		}
+	})
+
+	t.Run("constraints returning NaN are violated", func(t *testing.T) {
+		c := inequality{constraints: []func([]float64) float64{
+			sum,
+			func([]float64) float64 { return math.NaN() },
+		}}
+		if v := c.violation([]float64{0, 0}); !math.IsInf(v, 1) {
+			t.Errorf("expected +Inf got %v", v)
+		}
+	})
+
+	t.Run("merit functions reject constraints returning NaN", func(t *testing.T) {
+		// sqrt(x)-1 is NaN below 0, where the objective function keeps
+		// decreasing.
+		root := func(x []float64) float64 {
+			return math.Sqrt(x[0]) - 1
+		}
+		for name, handling := range map[string]InequalityHandling{
+			"penalty":              InequalityPenalty,
+			"augmented lagrangian": InequalityAugmentedLagrangian,
+		} {
+			t.Run(name, func(t *testing.T) {
+				options := NewOptions()
+				options.Tolerance = 1e-12
+				options.InequalityConstraints = []func([]float64) float64{root}
+				options.InequalityHandling = handling
+				options.NonFinite = NonFiniteFail
+
+				var calls int
+				result, err := RunWithResult(func(x []float64) float64 {
+					calls++
+					if x[0] < 0 {
+						t.Errorf("expected the objective function not to be called at %v", x)
+					}
+					return math.Pow(x[0]+2, 2)
+				}, []float64{0.5}, options)
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				expectPoint(t, Point{X: []float64{0}, F: 4}, result.Point, 6)
+				if result.Evaluations != calls {
+					t.Errorf("expected only the %d objective function calls to be counted got %d", calls, result.Evaluations)
+				}
+			})
+		}
+	})
+
+	t.Run("with box constraints", func(t *testing.T) {
+		constraints := []Constraint{{Min: 0, Max: 0.3}, {Min: 0, Max: 1}}
+		options := NewOptions()
+		options.Tolerance = 1e-12
+		options.Constraints = constraints
+		options.InequalityConstraints = []func([]float64) float64{sum}
+		options.InequalityHandling = InequalityAugmentedLagrangian
+
+		result, err := RunE(context.Background(), objectiveE(feasibleObjective(t, constraints, objective)), []float64{0.1, 0.1}, options)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		expectPoint(t, Point{X: []float64{0.3, 0.7}, F: 0.58}, result.Point, 4)
+	})
+}
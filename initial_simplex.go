@@ -0,0 +1,200 @@
+package neldermead
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+const (
+	// DefaultStep is the offset from x0 used by the default initial simplex.
+	DefaultStep = 1.0
+
+	// DefaultRelativeStep is the relative offset used by MATLAB's fminsearch for non-zero coordinates.
+	DefaultRelativeStep = 0.05
+
+	// DefaultZeroStep is the absolute offset used by MATLAB's fminsearch for coordinates that are zero.
+	DefaultZeroStep = 0.00025
+)
+
+// InitialSimplex builds the simplex an optimization starts from. The first vertex of the simplex is always
+// x0. Use StepSimplex, RelativeSimplex, RegularSimplex, ConstraintRangeSimplex, or GivenSimplex to create one.
+//
+// The size of the initial simplex should match the scale of the problem. A simplex that is much too small
+// for the problem may converge prematurely while one that is much too large wastes evaluations.
+type InitialSimplex interface {
+	initialSimplex(x0 []float64, constraints []Constraint) (Simplex, error)
+}
+
+type initialSimplexFunc func(x0 []float64, constraints []Constraint) (Simplex, error)
+
+func (fn initialSimplexFunc) initialSimplex(x0 []float64, constraints []Constraint) (Simplex, error) {
+	return fn(x0, constraints)
+}
+
+// StepSimplex offsets each coordinate of x0 by the corresponding step to create the other n vertices.
+// A single step is used for every dimension. Otherwise, there must be one step per dimension.
+// When a constraint is set and the offset vertex would be outside the bounds, the step is taken in the
+// opposite direction.
+func StepSimplex(steps ...float64) InitialSimplex {
+	return stepSimplex(steps)
+}
+
+type stepSimplex []float64
+
+func (steps stepSimplex) initialSimplex(x0 []float64, constraints []Constraint) (Simplex, error) {
+	if len(steps) != 1 && len(steps) != len(x0) {
+		return Simplex{}, errors.New("invalid initial simplex: the number of steps must be 1 or match the length of x0")
+	}
+	return axisSimplex(x0, constraints, func(i int) float64 {
+		if len(steps) == 1 {
+			return steps[0]
+		}
+		return steps[i]
+	})
+}
+
+// reduce returns the steps of the free dimensions when there is one step for
+// each of the n dimensions.
+func (steps stepSimplex) reduce(n int, free []int) InitialSimplex {
+	if len(steps) != n {
+		return steps
+	}
+	reduced := make(stepSimplex, len(free))
+	for k, i := range free {
+		reduced[k] = steps[i]
+	}
+	return reduced
+}
+
+// RelativeSimplex offsets each coordinate of x0 relative to its magnitude as suggested by L. Pfeffer and
+// used by MATLAB's fminsearch. A non-zero coordinate x0[i] is offset by relativeStep*x0[i] and a coordinate
+// that is zero is offset by zeroStep. DefaultRelativeStep and DefaultZeroStep are the values fminsearch uses.
+func RelativeSimplex(relativeStep, zeroStep float64) InitialSimplex {
+	return initialSimplexFunc(func(x0 []float64, constraints []Constraint) (Simplex, error) {
+		return axisSimplex(x0, constraints, func(i int) float64 {
+			if x0[i] == 0 {
+				return zeroStep
+			}
+			return relativeStep * x0[i]
+		})
+	})
+}
+
+// ConstraintRangeSimplex offsets each coordinate of x0 by a fraction of the range of its constraint.
+// The step is taken towards the opposite bound when the offset vertex would be outside the bounds.
+// It requires Options.Constraints to be set. Dimensions that are not bounded on both sides are offset
+// by DefaultStep.
+func ConstraintRangeSimplex(fraction float64) InitialSimplex {
+	return initialSimplexFunc(func(x0 []float64, constraints []Constraint) (Simplex, error) {
+		if len(constraints) == 0 {
+			return Simplex{}, errors.New("invalid initial simplex: ConstraintRangeSimplex requires constraints")
+		}
+		if fraction <= 0 || fraction > 1 {
+			return Simplex{}, errors.New("invalid initial simplex: the constraint range fraction must be in the range (0, 1]")
+		}
+		return axisSimplex(x0, constraints, func(i int) float64 {
+			if r := constraints[i].Max - constraints[i].Min; !math.IsInf(r, 0) {
+				return fraction * r
+			}
+			return DefaultStep
+		})
+	})
+}
+
+// RegularSimplex creates a regular simplex, one where every edge has the given length, with x0 as a vertex
+// (Spendley, Hext and Himsworth, 1962). The simplex is oriented towards increasing coordinates.
+func RegularSimplex(edgeLength float64) InitialSimplex {
+	return initialSimplexFunc(func(x0 []float64, _ []Constraint) (Simplex, error) {
+		if edgeLength <= 0 || math.IsInf(edgeLength, 0) || math.IsNaN(edgeLength) {
+			return Simplex{}, errors.New("invalid initial simplex: the edge length must be a positive number")
+		}
+		n := float64(len(x0))
+		var (
+			p = edgeLength / (n * math.Sqrt2) * (math.Sqrt(n+1) + n - 1)
+			q = edgeLength / (n * math.Sqrt2) * (math.Sqrt(n+1) - 1)
+		)
+		simplex := newSimplex(x0)
+		for i := 1; i < len(simplex.Points); i++ {
+			for j := range simplex.Points[i].X {
+				if i-1 == j {
+					simplex.Points[i].X[j] += p
+				} else {
+					simplex.Points[i].X[j] += q
+				}
+			}
+		}
+		return simplex, nil
+	})
+}
+
+// GivenSimplex uses the vertices of s as the initial simplex. The vertices are translated so that the
+// first vertex is at x0. Pass the first vertex of s as x0 to use the vertices as they are. When some
+// dimensions are fixed by their Constraint, the vertices of s must only have the free dimensions.
+func GivenSimplex(s Simplex) InitialSimplex {
+	return initialSimplexFunc(func(x0 []float64, _ []Constraint) (Simplex, error) {
+		if len(s.Points) != len(x0)+1 {
+			return Simplex{}, fmt.Errorf("invalid initial simplex: expected %d points got %d", len(x0)+1, len(s.Points))
+		}
+		for _, p := range s.Points {
+			if len(p.X) != len(x0) {
+				return Simplex{}, errors.New("invalid initial simplex: the length of each point must match the length of x0")
+			}
+		}
+		simplex := newSimplex(x0)
+		for i := 1; i < len(simplex.Points); i++ {
+			for j := range simplex.Points[i].X {
+				simplex.Points[i].X[j] += s.Points[i].X[j] - s.Points[0].X[j]
+			}
+		}
+		return simplex, nil
+	})
+}
+
+// newSimplex allocates a simplex for len(x0) dimensions where every vertex is x0.
+func newSimplex(x0 []float64) Simplex {
+	var (
+		n      = len(x0)
+		buf    = make([]float64, n*(n+1))
+		points = make([]Point, n+1)
+	)
+	for i := range points {
+		points[i].X = buf[i*n : (i+1)*n : (i+1)*n]
+		copy(points[i].X, x0)
+	}
+	return Simplex{Points: points}
+}
+
+// axisSimplex creates a simplex where vertex i+1 is x0 offset by step(i)
+// along dimension i.
+func axisSimplex(x0 []float64, constraints []Constraint, step func(i int) float64) (Simplex, error) {
+	simplex := newSimplex(x0)
+	for i := range x0 {
+		h := step(i)
+		if h == 0 || math.IsInf(h, 0) || math.IsNaN(h) {
+			return Simplex{}, fmt.Errorf("invalid initial simplex: the step for dimension %d must be a non-zero number", i)
+		}
+		if len(constraints) > 0 {
+			h = stepWithinConstraint(x0[i], h, constraints[i])
+		}
+		simplex.Points[i+1].X[i] += h
+	}
+	return simplex, nil
+}
+
+// stepWithinConstraint flips the direction of step when x+step is outside the
+// constraint and x-step is not.
+func stepWithinConstraint(x, step float64, c Constraint) float64 {
+	if v := x + step; v >= c.Min && v <= c.Max {
+		return step
+	}
+	if v := x - step; v >= c.Min && v <= c.Max {
+		return -step
+	}
+	// Neither direction fits inside the bounds so the step is taken towards
+	// the bound furthest from x and is clamped later.
+	if (step > 0) == (c.Max-x >= x-c.Min) {
+		return step
+	}
+	return -step
+}
@@ -0,0 +1,167 @@
+package neldermead
+
+import (
+	"math"
+	"testing"
+)
+
+func TestInitialSimplex(t *testing.T) {
+	for _, tt := range []struct {
+		name        string
+		initial     InitialSimplex
+		x0          []float64
+		constraints []Constraint
+		want        [][]float64
+		wantErr     bool
+	}{
+		{
+			name:    "single step",
+			initial: StepSimplex(2),
+			x0:      []float64{1, 1},
+			want:    [][]float64{{1, 1}, {3, 1}, {1, 3}},
+		},
+		{
+			name:    "step per dimension",
+			initial: StepSimplex(1e-6, 1e6),
+			x0:      []float64{0, 0},
+			want:    [][]float64{{0, 0}, {1e-6, 0}, {0, 1e6}},
+		},
+		{
+			name:    "wrong number of steps",
+			initial: StepSimplex(1, 2, 3),
+			x0:      []float64{0, 0},
+			wantErr: true,
+		},
+		{
+			name:    "zero step",
+			initial: StepSimplex(0),
+			x0:      []float64{0, 0},
+			wantErr: true,
+		},
+		{
+			name:        "step flips at the upper bound",
+			initial:     StepSimplex(1),
+			x0:          []float64{5, 0},
+			constraints: []Constraint{{Min: 0, Max: 5}, {Min: 0, Max: 5}},
+			want:        [][]float64{{5, 0}, {4, 0}, {5, 1}},
+		},
+		{
+			name:    "relative",
+			initial: RelativeSimplex(DefaultRelativeStep, DefaultZeroStep),
+			x0:      []float64{1e6, 0, -2e-6},
+			want:    [][]float64{{1e6, 0, -2e-6}, {1.05e6, 0, -2e-6}, {1e6, DefaultZeroStep, -2e-6}, {1e6, 0, -2.1e-6}},
+		},
+		{
+			name:        "constraint range",
+			initial:     ConstraintRangeSimplex(0.1),
+			x0:          []float64{10, 0},
+			constraints: []Constraint{{Min: 0, Max: 10}, {Min: -100, Max: 100}},
+			want:        [][]float64{{10, 0}, {9, 0}, {10, 20}},
+		},
+		{
+			name:        "constraint range with infinite bounds",
+			initial:     ConstraintRangeSimplex(0.1),
+			x0:          []float64{10, 0},
+			constraints: []Constraint{{Min: 0, Max: 10}, {Min: 0, Max: math.Inf(1)}},
+			want:        [][]float64{{10, 0}, {9, 0}, {10, DefaultStep}},
+		},
+		{
+			name:    "constraint range without constraints",
+			initial: ConstraintRangeSimplex(0.1),
+			x0:      []float64{10, 0},
+			wantErr: true,
+		},
+		{
+			name:        "constraint range fraction too large",
+			initial:     ConstraintRangeSimplex(2),
+			x0:          []float64{10},
+			constraints: []Constraint{{Min: 0, Max: 10}},
+			wantErr:     true,
+		},
+		{
+			name:    "given",
+			initial: GivenSimplex(Simplex{Points: []Point{{X: []float64{0, 0}}, {X: []float64{1, 0}}, {X: []float64{0, 2}}}}),
+			x0:      []float64{1, 1},
+			want:    [][]float64{{1, 1}, {2, 1}, {1, 3}},
+		},
+		{
+			name:    "given with the wrong number of points",
+			initial: GivenSimplex(Simplex{Points: []Point{{X: []float64{0, 0}}, {X: []float64{1, 0}}}}),
+			x0:      []float64{1, 1},
+			wantErr: true,
+		},
+		{
+			name:    "regular with a negative edge length",
+			initial: RegularSimplex(-1),
+			x0:      []float64{1, 1},
+			wantErr: true,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			simplex, err := tt.initial.initialSimplex(tt.x0, tt.constraints)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("initialSimplex() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if len(simplex.Points) != len(tt.want) {
+				t.Fatalf("expected %d points got %d", len(tt.want), len(simplex.Points))
+			}
+			for i, p := range simplex.Points {
+				for j := range p.X {
+					if math.Abs(p.X[j]-tt.want[i][j]) > 1e-9*math.Max(1, math.Abs(tt.want[i][j])) {
+						t.Errorf("expected point %d to be %v got %v", i, tt.want[i], p.X)
+						break
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestRegularSimplex(t *testing.T) {
+	for _, n := range []int{1, 2, 5, 20} {
+		x0 := make([]float64, n)
+		for i := range x0 {
+			x0[i] = float64(i)
+		}
+		simplex, err := RegularSimplex(3).initialSimplex(x0, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := range simplex.Points {
+			for j := i + 1; j < len(simplex.Points); j++ {
+				if d := distance(simplex.Points[i].X, simplex.Points[j].X); math.Abs(d-3) > 1e-9 {
+					t.Errorf("n=%d: expected every edge to have length 3 got %g between points %d and %d", n, d, i, j)
+				}
+			}
+		}
+	}
+}
+
+func TestOptions_InitialSimplex(t *testing.T) {
+	// The coordinates of the minimum differ by twelve orders of magnitude.
+	badlyScaled := func(x []float64) float64 {
+		a := (x[0] - 3e6) / 1e6
+		b := (x[1] - 2e-6) / 1e-6
+		return a*a + b*b
+	}
+
+	options := NewOptions()
+	options.Tolerance = 1e-12
+	options.InitialSimplex = RelativeSimplex(DefaultRelativeStep, DefaultZeroStep)
+
+	result, err := Run(badlyScaled, []float64{1e6, 1e-6}, options)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if math.Abs(result.X[0]-3e6) > 1 || math.Abs(result.X[1]-2e-6) > 1e-9 {
+		t.Errorf("expected x = [3e6 2e-6] got %v", result.X)
+	}
+
+	options.InitialSimplex = StepSimplex(1, 2, 3)
+	if _, err := Run(badlyScaled, []float64{1e6, 1e-6}, options); err == nil {
+		t.Errorf("expected an invalid initial simplex to fail")
+	}
+}
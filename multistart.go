@@ -0,0 +1,272 @@
+package neldermead
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"math"
+	"math/rand"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Sampling selects how MultiStart places starting points inside the constraint box.
+type Sampling int
+
+const (
+	// SamplingUniform draws every coordinate of every starting point independently and uniformly at random.
+	SamplingUniform Sampling = iota
+
+	// SamplingLatinHypercube divides the range of each dimension into one interval per starting point and
+	// places exactly one starting point in each interval. The intervals are paired at random across dimensions.
+	SamplingLatinHypercube
+
+	// SamplingHalton uses the Halton low-discrepancy sequence, which covers the box more evenly than random
+	// points. The sequence is deterministic so the random source is not used.
+	SamplingHalton
+)
+
+// MultiStartOptions configures MultiStart.
+type MultiStartOptions struct {
+	// Starts is the number of starting points. It must be greater than 0.
+	Starts int
+
+	// Sampling is how the starting points are placed inside Options.Constraints.
+	Sampling Sampling
+
+	// Source is the random source used to place the starting points and to seed the random source of each
+	// optimization. Use the same seed to get the same starting points and results again. When Source is nil,
+	// a source seeded with the current time is used.
+	Source rand.Source
+
+	// Parallelism is the maximum number of optimizations that run at the same time. When Parallelism is
+	// greater than 1, the objective function and Options.Observer must be safe for concurrent use.
+	// The results do not depend on Parallelism.
+	Parallelism int
+}
+
+// MultiStartResult is the outcome of MultiStart.
+type MultiStartResult struct {
+	// Best is the result of the optimization that found the lowest objective function value.
+	Best Result
+
+	// Optima has the result of the optimization from each starting point that was used sorted from best to
+	// worst.
+	Optima []Result
+}
+
+// MultiStart runs an optimization from each of several starting points placed inside Options.Constraints
+// and returns the local optima found along with the best one. Nelder-Mead only finds a local minimum near
+// where it starts, so searching from many starting points makes it more likely to find the global minimum.
+// Options.Constraints must set a finite Min and Max for every dimension.
+//
+// Each optimization gets its own Options.Source seeded from MultiStartOptions.Source, so optimizations
+// running at the same time never share a random source. Options.Source is not used.
+//
+// With Options.LinearEquality set, each starting point is projected onto the solutions of the equations. A
+// starting point whose projection is outside Options.Constraints, or with Options.InequalityHandling set to
+// InequalityBarrier one that violates the InequalityConstraints, is replaced with a point drawn uniformly at
+// random inside the constraints. When none of 100 such points is feasible, the starting point is dropped,
+// and MultiStart returns an error when every starting point is dropped.
+//
+// Optimizations that stop because the simplex collapsed are included in the results. When any other
+// optimization fails, MultiStart returns the error of the first failing starting point.
+func MultiStart(f Objective, options Options, multiStart MultiStartOptions) (MultiStartResult, error) {
+	if err := options.validate(); err != nil {
+		return MultiStartResult{}, err
+	}
+	if err := multiStart.validate(); err != nil {
+		return MultiStartResult{}, err
+	}
+	if len(options.Constraints) == 0 {
+		return MultiStartResult{}, errors.New("invalid Options parameter: MultiStart requires Constraints")
+	}
+	for _, c := range options.Constraints {
+		if math.IsInf(c.Max-c.Min, 0) {
+			return MultiStartResult{}, errors.New("invalid Options parameter: MultiStart requires every Constraint to have a finite Min and Max")
+		}
+	}
+	source := multiStart.Source
+	if source == nil {
+		source = rand.NewSource(time.Now().UnixNano())
+	}
+	rnd := rand.New(source)
+	starts, err := feasibleStarts(sample(multiStart.Sampling, multiStart.Starts, options.Constraints, rnd), options, rnd)
+	if err != nil {
+		return MultiStartResult{}, err
+	}
+	if len(starts) == 0 {
+		return MultiStartResult{}, errors.New("invalid Options parameter: MultiStart found no starting point that satisfies the constraints")
+	}
+	seeds := make([]int64, len(starts))
+	for i := range seeds {
+		seeds[i] = rnd.Int63()
+	}
+
+	var (
+		results = make([]Result, len(starts))
+		errs    = make([]error, len(starts))
+		next    = make(chan int)
+		wg      sync.WaitGroup
+	)
+	for range max(min(multiStart.Parallelism, len(starts)), 1) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				options := options
+				options.Source = rand.NewSource(seeds[i])
+				results[i], errs[i] = RunE(context.Background(), objectiveE(f), starts[i], options)
+			}
+		}()
+	}
+	for i := range starts {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil && !errors.As(err, new(ErrorSimplexCollapse)) {
+			return MultiStartResult{}, err
+		}
+	}
+	slices.SortStableFunc(results, func(a, b Result) int {
+		return cmp.Compare(a.F, b.F)
+	})
+	return MultiStartResult{Best: results[0], Optima: results}, nil
+}
+
+func (multiStart *MultiStartOptions) validate() error {
+	if multiStart.Starts <= 0 {
+		return errors.New("invalid MultiStartOptions parameter: Starts must be greater than 0")
+	}
+	if multiStart.Sampling < SamplingUniform || multiStart.Sampling > SamplingHalton {
+		return errors.New("invalid MultiStartOptions parameter: Sampling must be SamplingUniform, SamplingLatinHypercube, or SamplingHalton")
+	}
+	if multiStart.Parallelism < 0 {
+		return errors.New("invalid MultiStartOptions parameter: Parallelism must not be negative")
+	}
+	return nil
+}
+
+// maxRedraws limits how many points feasibleStarts draws to replace a
+// starting point that is infeasible.
+const maxRedraws = 100
+
+// feasibleStarts replaces the starting points that an optimization would
+// reject with uniformly drawn points and drops the ones that could not be
+// replaced. With Options.LinearEquality, the starting points are replaced
+// with their projections onto the solutions of the equations.
+func feasibleStarts(starts [][]float64, options Options, rnd *rand.Rand) ([][]float64, error) {
+	project := func(x []float64) []float64 { return x }
+	if len(options.LinearEquality.A) > 0 {
+		// The sampled points only check the length of the equations.
+		if err := options.validateX0(starts[0]); err != nil {
+			return nil, err
+		}
+		space, err := newNullSpace(options.LinearEquality, len(options.Constraints), options.Constraints)
+		if err != nil {
+			return nil, err
+		}
+		project = space.project
+	}
+	c := inequality{constraints: options.InequalityConstraints}
+	feasible := func(x []float64) bool {
+		for j, bound := range options.Constraints {
+			if !(x[j] >= bound.Min && x[j] <= bound.Max) {
+				return false
+			}
+		}
+		return options.InequalityHandling != InequalityBarrier || c.violation(x) <= 0
+	}
+	result := starts[:0]
+	for _, x := range starts {
+		start := project(x)
+		for k := 0; k < maxRedraws && !feasible(start); k++ {
+			start = project(sample(SamplingUniform, 1, options.Constraints, rnd)[0])
+		}
+		if feasible(start) {
+			result = append(result, start)
+		}
+	}
+	return result, nil
+}
+
+// sample returns count points inside the constraint box.
+func sample(sampling Sampling, count int, constraints []Constraint, rnd *rand.Rand) [][]float64 {
+	var (
+		n      = len(constraints)
+		buf    = make([]float64, count*n)
+		points = make([][]float64, count)
+	)
+	for i := range points {
+		points[i] = buf[i*n : (i+1)*n : (i+1)*n]
+	}
+	// Each coordinate is first chosen in the unit interval.
+	switch sampling {
+	case SamplingUniform:
+		for _, x := range points {
+			for j := range x {
+				x[j] = rnd.Float64()
+			}
+		}
+	case SamplingLatinHypercube:
+		for j := 0; j < n; j++ {
+			for i, stratum := range rnd.Perm(count) {
+				points[i][j] = (float64(stratum) + rnd.Float64()) / float64(count)
+			}
+		}
+	case SamplingHalton:
+		bases := primes(n)
+		for i, x := range points {
+			for j := range x {
+				// The sequence starts at index 1 because every coordinate of
+				// the point at index 0 is 0.
+				x[j] = radicalInverse(i+1, bases[j])
+			}
+		}
+	}
+	for _, x := range points {
+		for j, c := range constraints {
+			x[j] = min(c.Min+x[j]*(c.Max-c.Min), c.Max)
+		}
+	}
+	return points
+}
+
+// radicalInverse mirrors the digits of i in the given base around the
+// decimal point.
+func radicalInverse(i, base int) float64 {
+	var (
+		result   = 0.0
+		fraction = 1 / float64(base)
+	)
+	for ; i > 0; i /= base {
+		result += float64(i%base) * fraction
+		fraction /= float64(base)
+	}
+	return result
+}
+
+// primes returns the first n prime numbers.
+func primes(n int) []int {
+	result := make([]int, 0, n)
+	for candidate := 2; len(result) < n; candidate++ {
+		isPrime := true
+		for _, p := range result {
+			if p*p > candidate {
+				break
+			}
+			if candidate%p == 0 {
+				isPrime = false
+				break
+			}
+		}
+		if isPrime {
+			result = append(result, candidate)
+		}
+	}
+	return result
+}
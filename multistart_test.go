@@ -0,0 +1,241 @@
+package neldermead
+
+import (
+	"math"
+	"math/rand"
+	"sync"
+	"testing"
+)
+
+func TestMultiStart(t *testing.T) {
+	// The global minimum is at x = (-2, -2) with a deep basin while every
+	// other dimension has a shallow local minimum at x = 2.
+	twoBasins := func(x []float64) float64 {
+		sum := 0.0
+		for _, xi := range x {
+			sum += math.Min((xi+2)*(xi+2)-1, (xi-2)*(xi-2)-0.5)
+		}
+		return sum
+	}
+	constraints := []Constraint{{Min: -5, Max: 5}, {Min: -5, Max: 5}}
+
+	for _, tt := range []struct {
+		name     string
+		sampling Sampling
+	}{
+		{name: "uniform", sampling: SamplingUniform},
+		{name: "latin hypercube", sampling: SamplingLatinHypercube},
+		{name: "halton", sampling: SamplingHalton},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			options := NewOptions()
+			options.Constraints = constraints
+			result, err := MultiStart(twoBasins, options, MultiStartOptions{
+				Starts:   16,
+				Sampling: tt.sampling,
+				Source:   rand.NewSource(1),
+			})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			expectPoint(t, Point{X: []float64{-2, -2}, F: -2}, result.Best.Point, 3)
+			if len(result.Optima) != 16 {
+				t.Fatalf("expected 16 optima got %d", len(result.Optima))
+			}
+			for i := 1; i < len(result.Optima); i++ {
+				if result.Optima[i].F < result.Optima[i-1].F {
+					t.Errorf("expected optima to be sorted by F")
+				}
+			}
+		})
+	}
+
+	t.Run("reproducible", func(t *testing.T) {
+		run := func(parallelism int) MultiStartResult {
+			var mu sync.Mutex
+			objective := func(x []float64) float64 {
+				mu.Lock()
+				defer mu.Unlock()
+				return twoBasins(x)
+			}
+			options := NewOptions()
+			options.Constraints = constraints
+			result, err := MultiStart(objective, options, MultiStartOptions{
+				Starts:      8,
+				Sampling:    SamplingLatinHypercube,
+				Source:      rand.NewSource(42),
+				Parallelism: parallelism,
+			})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			return result
+		}
+		exp := run(0)
+		for _, parallelism := range []int{0, 4} {
+			got := run(parallelism)
+			for i := range exp.Optima {
+				expectSameResult(t, exp.Optima[i], got.Optima[i])
+			}
+		}
+	})
+
+	t.Run("Options.Source is not shared", func(t *testing.T) {
+		// The minimum is in a corner so BoundaryResample draws random
+		// numbers in every optimization. Each optimization must draw from
+		// its own source for the results not to depend on the order the
+		// optimizations run in.
+		run := func(parallelism int) MultiStartResult {
+			options := NewOptions()
+			options.Constraints = []Constraint{{Min: 0, Max: 1}, {Min: 0, Max: 1}}
+			options.BoundaryHandling = BoundaryResample
+			options.Source = rand.NewSource(3)
+			result, err := MultiStart(func(x []float64) float64 {
+				return -x[0] - x[1]
+			}, options, MultiStartOptions{
+				Starts:      8,
+				Source:      rand.NewSource(42),
+				Parallelism: parallelism,
+			})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			return result
+		}
+		exp := run(0)
+		for _, parallelism := range []int{0, 4} {
+			got := run(parallelism)
+			for i := range exp.Optima {
+				expectSameResult(t, exp.Optima[i], got.Optima[i])
+			}
+		}
+	})
+
+	t.Run("barrier redraws infeasible starting points", func(t *testing.T) {
+		sum := func(x []float64) float64 {
+			return x[0] + x[1] - 1
+		}
+		options := NewOptions()
+		options.Constraints = []Constraint{{Min: 0, Max: 1}, {Min: 0, Max: 1}}
+		options.InequalityConstraints = []func([]float64) float64{sum}
+		options.InequalityHandling = InequalityBarrier
+		result, err := MultiStart(func(x []float64) float64 {
+			return math.Pow(x[0]-1, 2) + math.Pow(x[1]-1, 2)
+		}, options, MultiStartOptions{
+			Starts: 10,
+			Source: rand.NewSource(1),
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(result.Optima) != 10 {
+			t.Errorf("expected 10 optima got %d", len(result.Optima))
+		}
+		expectPoint(t, Point{X: []float64{0.5, 0.5}, F: 0.5}, result.Best.Point, 2)
+
+		// No point satisfies a constraint that is violated everywhere.
+		options.InequalityConstraints = []func([]float64) float64{func([]float64) float64 { return 1 }}
+		if _, err := MultiStart(func([]float64) float64 { return 0 }, options, MultiStartOptions{Starts: 4}); err == nil {
+			t.Errorf("expected an error")
+		}
+	})
+
+	t.Run("linear equality redraws infeasible starting points", func(t *testing.T) {
+		constraints := []Constraint{{Min: 0, Max: 1}, {Min: 0, Max: 1}, {Min: 0, Max: 1}}
+		options := NewOptions()
+		options.Constraints = constraints
+		options.LinearEquality = LinearEquality{A: [][]float64{{1, 1, 1}}, B: []float64{1}}
+		result, err := MultiStart(feasibleObjective(t, constraints, func(x []float64) float64 {
+			return math.Pow(x[0]-0.5, 2) + math.Pow(x[1]-0.3, 2) + math.Pow(x[2]-0.2, 2)
+		}), options, MultiStartOptions{
+			Starts: 10,
+			Source: rand.NewSource(1),
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(result.Optima) != 10 {
+			t.Errorf("expected 10 optima got %d", len(result.Optima))
+		}
+		expectPoint(t, Point{X: []float64{0.5, 0.3, 0.2}, F: 0}, result.Best.Point, 3)
+
+		options.LinearEquality = LinearEquality{A: [][]float64{{1, 1}}, B: []float64{1}}
+		if _, err := MultiStart(func([]float64) float64 { return 0 }, options, MultiStartOptions{Starts: 4}); err == nil {
+			t.Errorf("expected an error for equations of the wrong length")
+		}
+	})
+
+	t.Run("requires constraints", func(t *testing.T) {
+		if _, err := MultiStart(twoBasins, NewOptions(), MultiStartOptions{Starts: 4}); err == nil {
+			t.Errorf("expected an error")
+		}
+	})
+
+	t.Run("requires finite constraints", func(t *testing.T) {
+		options := NewOptions()
+		options.Constraints = []Constraint{{Min: -5, Max: 5}, {Min: 0, Max: math.Inf(1)}}
+		if _, err := MultiStart(twoBasins, options, MultiStartOptions{Starts: 4}); err == nil {
+			t.Errorf("expected an error")
+		}
+	})
+
+	t.Run("invalid options", func(t *testing.T) {
+		options := NewOptions()
+		options.Constraints = constraints
+		for _, multiStart := range []MultiStartOptions{
+			{Starts: 0},
+			{Starts: 4, Sampling: SamplingHalton + 1},
+			{Starts: 4, Parallelism: -1},
+		} {
+			if _, err := MultiStart(twoBasins, options, multiStart); err == nil {
+				t.Errorf("expected an error for %+v", multiStart)
+			}
+		}
+	})
+}
+
+func TestSample(t *testing.T) {
+	constraints := []Constraint{{Min: -1, Max: 1}, {Min: 10, Max: 20}, {Min: 0, Max: 1e-3}}
+	const count = 50
+
+	for _, sampling := range []Sampling{SamplingUniform, SamplingLatinHypercube, SamplingHalton} {
+		points := sample(sampling, count, constraints, rand.New(rand.NewSource(7)))
+		if len(points) != count {
+			t.Fatalf("expected %d points got %d", count, len(points))
+		}
+		for _, x := range points {
+			for j, c := range constraints {
+				if x[j] < c.Min || x[j] > c.Max {
+					t.Errorf("sampling %d: expected %v to be inside %v", sampling, x, constraints)
+				}
+			}
+		}
+	}
+
+	t.Run("latin hypercube has one point per interval", func(t *testing.T) {
+		points := sample(SamplingLatinHypercube, count, constraints, rand.New(rand.NewSource(7)))
+		for j, c := range constraints {
+			intervals := make([]int, count)
+			for _, x := range points {
+				intervals[min(int((x[j]-c.Min)/(c.Max-c.Min)*count), count-1)]++
+			}
+			for i, points := range intervals {
+				if points != 1 {
+					t.Errorf("dimension %d: expected 1 point in interval %d got %d", j, i, points)
+				}
+			}
+		}
+	})
+
+	t.Run("halton", func(t *testing.T) {
+		points := sample(SamplingHalton, 4, []Constraint{{Min: 0, Max: 1}, {Min: 0, Max: 1}}, nil)
+		want := [][]float64{{0.5, 1.0 / 3}, {0.25, 2.0 / 3}, {0.75, 1.0 / 9}, {0.125, 4.0 / 9}}
+		for i := range want {
+			for j := range want[i] {
+				if math.Abs(points[i][j]-want[i][j]) > 1e-12 {
+					t.Errorf("expected point %d to be %v got %v", i, want[i], points[i])
+				}
+			}
+		}
+	})
+}
@@ -0,0 +1,274 @@
+package neldermead
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"sync/atomic"
+)
+
+// RunParallel minimizes f using the parallel variant of the Nelder-Mead algorithm described by Lee and
+// Wiswall (2007). Instead of replacing only the worst vertex, each iteration reflects the p worst vertices
+// through the centroid of the other n+1-p vertices and expands or contracts each of them independently.
+// The simplex is shrunk only when the contraction of every one of the p vertices failed. p must be in the
+// range [1, n] where n is the length of x0. With p set to 1, RunParallel takes the same steps as Run. When some
+// dimensions are fixed by their Constraint, at most one vertex per free dimension is moved.
+//
+// The objective function is called for up to p points concurrently, or Options.Parallelism points when it
+// is set, so it must be safe for concurrent use. Larger values of p make each iteration take more objective
+// function calls but allow more of them to run at the same time. This pays off for problems with many
+// dimensions and objective functions that are expensive to evaluate. Values of p that are small compared to
+// n work best. As p approaches n, the moved vertices tend to fall into a lower dimensional subspace and the
+// optimization can stall far from a minimum.
+//
+// Options.Speculative is not supported. IterationInfo.Operation reports the operation applied to the worst
+// vertex that was replaced, or OperationShrink.
+func RunParallel(f Objective, x0 []float64, p int, options Options) (Point, error) {
+	ctx := context.Background()
+	result, err := RunParallelE(ctx, objectiveE(f), x0, p, options)
+	return bestPoint(ctx, result, err)
+}
+
+// RunParallelE runs the same optimization as RunParallel with the context, error handling, and Result
+// described by RunE.
+func RunParallelE(ctx context.Context, f ObjectiveE, x0 []float64, p int, options Options) (Result, error) {
+	if p < 1 || p > len(x0) {
+		return Result{}, fmt.Errorf("invalid number of parallel vertices: p must be in the range [1, %d]", len(x0))
+	}
+	if options.Speculative {
+		return Result{}, errors.New("invalid Options parameter: Speculative is not supported by RunParallel")
+	}
+	return runE(ctx, f, x0, p, options)
+}
+
+// stepParallel replaces the o.parallelVertices worst vertices following
+// Lee and Wiswall. Every vertex is compared to the simplex as it was at the
+// start of the iteration so the decisions do not depend on each other. The
+// reflected points are evaluated as one batch and the expanded or contracted
+// points as another.
+func (o *optimizer) stepParallel() (Operation, error) {
+	var (
+		simplex       = o.simplex
+		options       = o.options
+		p             = o.parallelVertices
+		first         = len(simplex.Points) - p
+		bestPoint     = &simplex.Points[0]
+		retainedWorst = &simplex.Points[first-1]
+		reflected     = o.parallelBuffer()[:p]
+		moved         = o.parallelBuffer()[p:]
+		workers       = options.Parallelism
+	)
+	if workers == 0 {
+		workers = p
+	}
+	setZero(o.centroid)
+	for i := 0; i < first; i++ {
+		for j, x := range simplex.Points[i].X {
+			o.centroid[j] += x
+		}
+	}
+	for j := range o.centroid {
+		o.centroid[j] /= float64(first)
+	}
+
+	// keepReflected replaces the vertices with reflected points that are
+	// better than every vertex when the iteration can not be completed.
+	keepReflected := func(evaluated int) {
+		for k, r := range reflected[:evaluated] {
+			if r.F < bestPoint.F {
+				simplex.replacePoint(first+k, *r)
+			}
+		}
+	}
+
+	for k, r := range reflected {
+		o.move(r.X, &simplex.Points[first+k], options.Alpha)
+	}
+	if evaluated, err := o.evaluateConcurrently(reflected, workers); err != nil {
+		keepReflected(evaluated)
+		return 0, err
+	}
+	for k, r := range reflected {
+		vertex := &simplex.Points[first+k]
+		switch {
+		case r.F < retainedWorst.F:
+			o.move(moved[k].X, r, options.Gamma)
+		case r.F < vertex.F:
+			o.move(moved[k].X, r, options.Beta)
+		default:
+			o.move(moved[k].X, vertex, options.Beta)
+		}
+	}
+	if _, err := o.evaluateConcurrently(moved, workers); err != nil {
+		keepReflected(len(reflected))
+		return 0, err
+	}
+
+	// A reflected point that is only better than its vertex replaces it even
+	// when the contraction fails, but the simplex is still shrunk when no
+	// vertex was improved further.
+	var (
+		operation = OperationShrink
+		shrink    = true
+	)
+	for k, r := range reflected {
+		var (
+			vertex = &simplex.Points[first+k]
+			m      = moved[k]
+		)
+		switch {
+		case r.F < retainedWorst.F:
+			shrink = false
+			if m.F < r.F {
+				operation = OperationExpand
+				simplex.replacePoint(first+k, *m)
+			} else {
+				operation = OperationReflect
+				simplex.replacePoint(first+k, *r)
+			}
+		case r.F < vertex.F:
+			if m.F < r.F {
+				shrink = false
+				operation = OperationContractInside
+				simplex.replacePoint(first+k, *m)
+			} else {
+				operation = OperationReflect
+				simplex.replacePoint(first+k, *r)
+			}
+		default:
+			if m.F < vertex.F {
+				shrink = false
+				operation = OperationContractOutside
+				simplex.replacePoint(first+k, *m)
+			}
+		}
+	}
+	if shrink {
+		return OperationShrink, nil
+	}
+	return operation, nil
+}
+
+// parallelBuffer returns scratch points for the reflected points followed by
+// the expanded or contracted points of stepParallel.
+func (o *optimizer) parallelBuffer() []*Point {
+	if o.parallelPoints == nil {
+		var (
+			n      = len(o.simplex.Points) - 1
+			p      = o.parallelVertices
+			buf    = make([]float64, 2*p*n)
+			points = make([]Point, 2*p)
+		)
+		o.parallelPoints = make([]*Point, 2*p)
+		for i := range points {
+			points[i].X = buf[i*n : (i+1)*n : (i+1)*n]
+			o.parallelPoints[i] = &points[i]
+		}
+	}
+	return o.parallelPoints
+}
+
+// evaluateBatch evaluates independent points using up to Options.Parallelism
+// concurrent objective function calls. It returns the number of leading
+// points that were evaluated. Results are recorded in the order of points so
+// the outcome does not depend on the order the calls complete in.
+func (o *optimizer) evaluateBatch(points []*Point) (int, error) {
+	return o.evaluateConcurrently(points, o.options.Parallelism)
+}
+
+// evaluateCandidates computes every point an iteration may need and
+// evaluates them concurrently: the reflected, expanded, outside contracted,
+// and inside contracted points in that order.
+func (o *optimizer) evaluateCandidates(worstPoint *Point) error {
+	options := o.options
+	o.move(o.reflectedPoint.X, worstPoint, options.Alpha)
+	o.move(o.expandedPoint.X, &o.reflectedPoint, options.Gamma)
+	o.move(o.contractedPoint.X, worstPoint, options.Beta)
+	o.move(o.insideContractedPoint.X, &o.reflectedPoint, options.Beta)
+	for _, p := range o.candidates {
+		p.F = math.NaN()
+	}
+	_, err := o.evaluateConcurrently(o.candidates, len(o.candidates))
+	return err
+}
+
+func (o *optimizer) evaluateConcurrently(points []*Point, workers int) (int, error) {
+	workers = min(workers, len(points))
+	if workers <= 1 {
+		for i, p := range points {
+			if err := o.evaluate(p); err != nil {
+				return i, err
+			}
+		}
+		return len(points), nil
+	}
+
+	// The evaluation budget is reserved up front so that the points that are
+	// evaluated are always the leading ones.
+	var (
+		n         = len(points)
+		budgetErr error
+	)
+	if maxEvaluations := o.options.MaxEvaluations; maxEvaluations > 0 && o.evaluations+n > maxEvaluations {
+		n, budgetErr = maxEvaluations-o.evaluations, errMaxEvaluations
+	}
+
+	for _, p := range points[:n] {
+		o.feasible(p.X)
+	}
+
+	type evaluation struct {
+		y                  float64
+		err                error
+		called, infeasible bool
+	}
+	var (
+		evaluations = make([]evaluation, n)
+		next        atomic.Int64
+		wg          sync.WaitGroup
+	)
+	for range min(workers, n) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				i := int(next.Add(1) - 1)
+				if i >= n {
+					return
+				}
+				if err := o.interrupted(); err != nil {
+					evaluations[i].err = err
+					continue
+				}
+				y, err := o.call(points[i].X)
+				if err == errInfeasible {
+					evaluations[i].infeasible = true
+					continue
+				}
+				evaluations[i] = evaluation{y: y, err: err, called: true}
+			}
+		}()
+	}
+	wg.Wait()
+
+	for _, e := range evaluations {
+		if e.called {
+			o.evaluations++
+		}
+	}
+	for i, e := range evaluations {
+		if e.infeasible {
+			points[i].F = math.Inf(1)
+			continue
+		}
+		if !e.called {
+			return i, e.err
+		}
+		if err := o.record(points[i], e.y, e.err); err != nil {
+			return i, err
+		}
+	}
+	return n, budgetErr
+}
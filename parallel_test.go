@@ -0,0 +1,383 @@
+package neldermead
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestOptions_Parallelism(t *testing.T) {
+	// The objective has flat steps so contractions often fail and the
+	// simplex is shrunk, which exercises batch evaluation.
+	stepped := func(x []float64) float64 {
+		sum := 0.0
+		for i, xi := range x {
+			sum += float64(i+1) * math.Floor(math.Abs(xi-1)*4)
+		}
+		return sum
+	}
+
+	t.Run("same result as sequential", func(t *testing.T) {
+		options := NewOptions()
+		options.MaxIterations = 500
+
+		var results []Result
+		for _, parallelism := range []int{0, 1, 3, 8, 64} {
+			options.Parallelism = parallelism
+			result, err := RunWithResult(stepped, make([]float64, 12), options)
+			if err != nil {
+				t.Fatalf("parallelism %d: unexpected error: %v", parallelism, err)
+			}
+			results = append(results, result)
+		}
+		for i, result := range results[1:] {
+			expectSameResult(t, results[0], result)
+			if t.Failed() {
+				t.Fatalf("result %d differs from the sequential result", i+1)
+			}
+		}
+	})
+
+	t.Run("bounded concurrency", func(t *testing.T) {
+		var running, maxRunning atomic.Int64
+		objective := func(x []float64) float64 {
+			n := running.Add(1)
+			defer running.Add(-1)
+			for {
+				m := maxRunning.Load()
+				if n <= m || maxRunning.CompareAndSwap(m, n) {
+					break
+				}
+			}
+			time.Sleep(time.Millisecond)
+			return stepped(x)
+		}
+
+		options := NewOptions()
+		options.MaxIterations = 20
+		options.Parallelism = 4
+		if _, err := Run(objective, make([]float64, 16), options); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got := maxRunning.Load(); got < 2 || got > 4 {
+			t.Errorf("expected between 2 and 4 concurrent calls got %d", got)
+		}
+	})
+
+	t.Run("max evaluations", func(t *testing.T) {
+		for _, maxEvaluations := range []int{3, 13, 40} {
+			t.Run(fmt.Sprint(maxEvaluations), func(t *testing.T) {
+				var calls atomic.Int64
+				objective := func(x []float64) float64 {
+					calls.Add(1)
+					return stepped(x)
+				}
+				options := NewOptions()
+				options.Parallelism = 8
+				options.MaxEvaluations = maxEvaluations
+
+				result, err := RunWithResult(objective, make([]float64, 12), options)
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if got := calls.Load(); got != int64(maxEvaluations) || result.Evaluations != maxEvaluations {
+					t.Errorf("expected exactly %d evaluations got %d (result reports %d)", maxEvaluations, got, result.Evaluations)
+				}
+				if result.Termination != TerminationMaxEvaluations {
+					t.Errorf("expected termination %q got %q", TerminationMaxEvaluations, result.Termination)
+				}
+			})
+		}
+	})
+
+	t.Run("objective error", func(t *testing.T) {
+		errFailed := errors.New("failed")
+		var mu sync.Mutex
+		objective := func(x []float64) (float64, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			if x[5] != 0 {
+				return 0, errFailed
+			}
+			return stepped(x), nil
+		}
+		options := NewOptions()
+		options.Parallelism = 8
+
+		_, err := RunE(context.Background(), objective, make([]float64, 12), options)
+		var objectiveErr ErrorObjective
+		if !errors.As(err, &objectiveErr) {
+			t.Fatalf("expected an ErrorObjective got %v", err)
+		}
+		// The error reported is the one for the first failing vertex in
+		// simplex order regardless of which call returned first.
+		if objectiveErr.X[5] != 1 {
+			t.Errorf("expected the error for the vertex offset along dimension 5 got %v", objectiveErr.X)
+		}
+	})
+}
+
+func TestOptions_Speculative(t *testing.T) {
+	rosenbrock := func(x []float64) float64 {
+		sum := 0.0
+		for i := 0; i+1 < len(x); i++ {
+			sum += math.Pow(1-x[i], 2) + 100*math.Pow(x[i+1]-x[i]*x[i], 2)
+		}
+		return sum
+	}
+
+	run := func(t *testing.T, options Options) (Result, []IterationInfo) {
+		t.Helper()
+		var infos []IterationInfo
+		options.Observer = func(info IterationInfo) bool {
+			infos = append(infos, info)
+			return false
+		}
+		result, err := RunWithResult(rosenbrock, []float64{-1.2, 1, -0.5, 0.8}, options)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return result, infos
+	}
+
+	options := NewOptions()
+	options.MaxIterations = 2000
+	sequential, sequentialInfos := run(t, options)
+	options.Speculative = true
+	speculative, speculativeInfos := run(t, options)
+
+	if len(sequentialInfos) != len(speculativeInfos) {
+		t.Fatalf("expected %d iterations got %d", len(sequentialInfos), len(speculativeInfos))
+	}
+	for i, exp := range sequentialInfos {
+		got := speculativeInfos[i]
+		if exp.Operation != got.Operation || exp.Best.F != got.Best.F {
+			t.Fatalf("iteration %d: expected %s with best %g got %s with best %g", i, exp.Operation, exp.Best.F, got.Operation, got.Best.F)
+		}
+	}
+	if sequential.F != speculative.F || sequential.Iterations != speculative.Iterations || sequential.Termination != speculative.Termination {
+		t.Errorf("expected F=%g iterations=%d termination=%q got F=%g iterations=%d termination=%q",
+			sequential.F, sequential.Iterations, sequential.Termination, speculative.F, speculative.Iterations, speculative.Termination)
+	}
+	for i := range sequential.X {
+		if sequential.X[i] != speculative.X[i] {
+			t.Errorf("expected x = %v got %v", sequential.X, speculative.X)
+			break
+		}
+	}
+	if speculative.Evaluations <= sequential.Evaluations {
+		t.Errorf("expected speculative evaluation to use more than %d evaluations got %d", sequential.Evaluations, speculative.Evaluations)
+	}
+}
+
+func TestRunParallel(t *testing.T) {
+	quadratic := func(x []float64) float64 {
+		sum := 0.0
+		for i, xi := range x {
+			sum += float64(i+1) * (xi - 1) * (xi - 1)
+		}
+		return sum
+	}
+
+	t.Run("one vertex is the standard algorithm", func(t *testing.T) {
+		// iterate hands p = 1 to step, so stepParallel is called directly
+		// to compare it to step. The objective function has enough local
+		// structure for every operation to be taken.
+		objective := func(x []float64) float64 {
+			sum := 0.0
+			for i, xi := range x {
+				sum += math.Sin(3*xi+float64(i)) + 0.1*xi*xi
+			}
+			return sum
+		}
+		var optimizers [2]*optimizer
+		for i := range optimizers {
+			o, err := newOptimizer(context.Background(), objectiveE(objective), make([]float64, 6), NewOptions())
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			o.parallelVertices = 1
+			if err := o.evaluateSimplex(); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			optimizers[i] = o
+		}
+		sequential, parallel := optimizers[0], optimizers[1]
+		for i := 0; i < 200; i++ {
+			exp, err := sequential.step()
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			got, err := parallel.stepParallel()
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if exp != got {
+				t.Fatalf("iteration %d: expected %s got %s", i, exp, got)
+			}
+			for _, o := range optimizers {
+				if got == OperationShrink {
+					if err := o.shrink(); err != nil {
+						t.Fatalf("unexpected error: %v", err)
+					}
+				}
+				sortSimplex(o.simplex)
+			}
+			for k := range sequential.simplex.Points {
+				exp, got := sequential.simplex.Points[k], parallel.simplex.Points[k]
+				if exp.F != got.F || !slices.Equal(exp.X, got.X) {
+					t.Fatalf("iteration %d: expected vertex %d to be %v got %v", i, k, exp, got)
+				}
+			}
+		}
+		if sequential.evaluations != parallel.evaluations {
+			t.Errorf("expected %d evaluations got %d", sequential.evaluations, parallel.evaluations)
+		}
+	})
+
+	for _, p := range []int{2, 3} {
+		t.Run(fmt.Sprintf("p=%d", p), func(t *testing.T) {
+			var (
+				running, maxRunning atomic.Int64
+				operations          = make(map[Operation]int)
+			)
+			objective := func(x []float64) float64 {
+				n := running.Add(1)
+				defer running.Add(-1)
+				for {
+					m := maxRunning.Load()
+					if n <= m || maxRunning.CompareAndSwap(m, n) {
+						break
+					}
+				}
+				return quadratic(x)
+			}
+			options := NewOptions()
+			options.Tolerance = 1e-12
+			options.MaxIterations = 20000
+			options.Observer = func(info IterationInfo) bool {
+				operations[info.Operation]++
+				return false
+			}
+
+			result, err := RunParallelE(context.Background(), objectiveE(objective), make([]float64, 10), p, options)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if result.Termination != TerminationConverged {
+				t.Errorf("expected termination %q got %q", TerminationConverged, result.Termination)
+			}
+			for i, x := range result.X {
+				if math.Abs(x-1) > 1e-3 {
+					t.Errorf("expected x%d = 1 got %g", i, x)
+				}
+			}
+			if got := maxRunning.Load(); got > int64(p) {
+				t.Errorf("expected at most %d concurrent calls got %d", p, got)
+			}
+			if operations[OperationReflect] == 0 {
+				t.Errorf("expected at least one reflection got %v", operations)
+			}
+		})
+	}
+
+	t.Run("invalid number of parallel vertices", func(t *testing.T) {
+		for _, p := range []int{0, 4} {
+			if _, err := RunParallel(quadratic, make([]float64, 3), p, NewOptions()); err == nil {
+				t.Errorf("expected an error for p = %d", p)
+			}
+		}
+	})
+
+	t.Run("speculative", func(t *testing.T) {
+		options := NewOptions()
+		options.Speculative = true
+		if _, err := RunParallel(quadratic, make([]float64, 3), 2, options); err == nil {
+			t.Errorf("expected an error")
+		}
+	})
+}
+
+func BenchmarkRunParallel(b *testing.B) {
+	quadratic := func(x []float64) float64 {
+		sum := 0.0
+		for i, xi := range x {
+			sum += float64(i+1) * (xi - 1) * (xi - 1)
+		}
+		return sum
+	}
+	rosenbrock := func(x []float64) float64 {
+		sum := 0.0
+		for i := 0; i < len(x)-1; i++ {
+			a, c := 1-x[i], x[i+1]-x[i]*x[i]
+			sum += a*a + 100*c*c
+		}
+		return sum
+	}
+	// expensive simulates an objective function that takes a while to
+	// evaluate, which is when evaluating points concurrently pays off.
+	expensive := func(f Objective) Objective {
+		return func(x []float64) float64 {
+			for start := time.Now(); time.Since(start) < 20*time.Microsecond; {
+			}
+			return f(x)
+		}
+	}
+	for _, problem := range []struct {
+		name      string
+		objective Objective
+	}{
+		{name: "quadratic", objective: quadratic},
+		{name: "rosenbrock", objective: rosenbrock},
+		{name: "expensive_quadratic", objective: expensive(quadratic)},
+	} {
+		for _, n := range []int{16, 32} {
+			for _, p := range []int{0, 1, 2, n / 4, n / 2} {
+				name := fmt.Sprintf("%s/n=%d/p=%d", problem.name, n, p)
+				if p == 0 {
+					name = fmt.Sprintf("%s/n=%d/Run", problem.name, n)
+				}
+				b.Run(name, func(b *testing.B) {
+					options := NewOptions()
+					options.MaxIterations = 100 * n
+					options.Tolerance = 1e-8
+					var result Result
+					for i := 0; i < b.N; i++ {
+						var err error
+						if p == 0 {
+							result, err = RunWithResult(problem.objective, make([]float64, n), options)
+						} else {
+							result, err = RunParallelE(context.Background(), objectiveE(problem.objective), make([]float64, n), p, options)
+						}
+						if err != nil {
+							b.Fatal(err)
+						}
+					}
+					b.ReportMetric(float64(result.Iterations), "iters")
+					b.ReportMetric(float64(result.Evaluations), "evals")
+					b.ReportMetric(result.F, "f")
+				})
+			}
+		}
+	}
+}
+
+func expectSameResult(t *testing.T, exp, got Result) {
+	t.Helper()
+	if exp.F != got.F || exp.Iterations != got.Iterations || exp.Evaluations != got.Evaluations || exp.Termination != got.Termination {
+		t.Errorf("expected F=%g iterations=%d evaluations=%d termination=%q got F=%g iterations=%d evaluations=%d termination=%q",
+			exp.F, exp.Iterations, exp.Evaluations, exp.Termination, got.F, got.Iterations, got.Evaluations, got.Termination)
+	}
+	for i := range exp.X {
+		if exp.X[i] != got.X[i] {
+			t.Errorf("expected x = %v got %v", exp.X, got.X)
+			break
+		}
+	}
+}
@@ -2,9 +2,13 @@ package neldermead
 
 import (
 	"cmp"
+	"context"
 	"errors"
+	"fmt"
 	"math"
+	"math/rand"
 	"slices"
+	"time"
 )
 
 const (
@@ -27,6 +31,42 @@ func (s *Simplex) replacePoint(i int, newPoint Point) {
 	newPoint.F = 0
 }
 
+// pointers returns a pointer to each point of the simplex.
+func (s *Simplex) pointers() []*Point {
+	points := make([]*Point, len(s.Points))
+	for i := range s.Points {
+		points[i] = &s.Points[i]
+	}
+	return points
+}
+
+// spread returns the difference in objective function values between the
+// worst and best points. The simplex must be sorted.
+func (s *Simplex) spread() float64 {
+	return math.Abs(s.Points[0].F - s.Points[len(s.Points)-1].F)
+}
+
+// size returns the largest distance between the first point and any other
+// point. The simplex must be sorted.
+func (s *Simplex) size() float64 {
+	d := 0.0
+	for i := 1; i < len(s.Points); i++ {
+		d = max(d, distance(s.Points[0].X, s.Points[i].X))
+	}
+	return d
+}
+
+// diameter returns the largest distance between two points of the simplex.
+func (s *Simplex) diameter() float64 {
+	d := 0.0
+	for i := range s.Points {
+		for j := i + 1; j < len(s.Points); j++ {
+			d = max(d, distance(s.Points[i].X, s.Points[j].X))
+		}
+	}
+	return d
+}
+
 func (s *Simplex) isCollapsed(threshold float64) bool {
 	if threshold == 0 {
 		return false
@@ -42,6 +82,110 @@ type Point struct {
 
 type Objective = func(x []float64) float64
 
+// ObjectiveE is an objective function that may fail. How failures are handled is configured with
+// Options.ObjectiveErrors.
+type ObjectiveE = func(x []float64) (float64, error)
+
+func objectiveE(f Objective) ObjectiveE {
+	return func(x []float64) (float64, error) {
+		return f(x), nil
+	}
+}
+
+// ObjectiveErrorPolicy configures what happens when an ObjectiveE returns an error.
+type ObjectiveErrorPolicy int
+
+const (
+	// ObjectiveErrorAbort stops the optimization and returns an ErrorObjective wrapping the error.
+	ObjectiveErrorAbort ObjectiveErrorPolicy = iota
+
+	// ObjectiveErrorInfeasible treats a point where the objective function failed as infeasible
+	// by setting its value to +Inf and continues the optimization.
+	ObjectiveErrorInfeasible
+)
+
+// ConvergenceCriteria selects which convergence criteria must be satisfied for an optimization to converge.
+type ConvergenceCriteria int
+
+const (
+	// ConvergenceFAndX requires the spread of the objective function values to be below Options.Tolerance
+	// or Options.RelativeTolerance and, when Options.XTolerance is set, the size of the simplex to be below Options.XTolerance.
+	ConvergenceFAndX ConvergenceCriteria = iota
+
+	// ConvergenceF only requires the spread of the objective function values to be below Options.Tolerance
+	// or Options.RelativeTolerance.
+	ConvergenceF
+
+	// ConvergenceX only requires the size of the simplex to be below Options.XTolerance.
+	ConvergenceX
+)
+
+// NonFinitePolicy configures how NaN and infinite objective function values are handled.
+type NonFinitePolicy int
+
+const (
+	// NonFiniteAsInf treats NaN as +Inf so that a point where the objective function is NaN is
+	// considered worse than any other point.
+	NonFiniteAsInf NonFinitePolicy = iota
+
+	// NonFiniteReject never lets a point where the objective function is NaN into the simplex. A NaN
+	// reflection leads to a contraction, a NaN expansion keeps the reflected point, and a NaN contraction
+	// leads to a shrink. Vertices of the initial simplex or of a shrink where the objective
+	// function is NaN are moved towards the best vertex (scaled by Delta) and evaluated again. Each
+	// attempt counts as an evaluation. A vertex that is still NaN after a few attempts is given the
+	// value +Inf.
+	NonFiniteReject
+
+	// NonFiniteFail stops the optimization with an ErrorNonFiniteObjective when the objective function
+	// returns NaN, +Inf or -Inf.
+	NonFiniteFail
+)
+
+// minNormalFloat64 is the smallest positive normal float64. It stops
+// RelativeTolerance from being disabled when the best value is 0.
+const minNormalFloat64 = 0x1p-1022
+
+// maxRejections limits how many times a NaN vertex is moved towards the best
+// vertex when Options.NonFinite is NonFiniteReject.
+const maxRejections = 8
+
+// RestartPolicy selects when the simplex is rebuilt around the best point found so far. Policies can be
+// combined with |.
+type RestartPolicy int
+
+const (
+	// RestartOnCollapse rebuilds the simplex instead of returning ErrorSimplexCollapse when the average edge
+	// length falls below Options.CollapseThreshold.
+	RestartOnCollapse RestartPolicy = 1 << iota
+
+	// RestartOnStagnation rebuilds the simplex when the optimization has not made progress for
+	// Options.StagnationIterations iterations.
+	RestartOnStagnation
+)
+
+// BoundaryHandling selects how points outside Options.Constraints are moved back inside the bounds before
+// the objective function is evaluated.
+type BoundaryHandling int
+
+const (
+	// BoundaryClamp moves each coordinate that is outside its bounds to the nearest bound. Vertices can pile
+	// up on a bound, which makes the simplex degenerate when the minimum is not on the bound.
+	BoundaryClamp BoundaryHandling = iota
+
+	// BoundaryReflect mirrors each coordinate that is outside its bounds back inside at the bound it crossed.
+	BoundaryReflect
+
+	// BoundaryWrap treats each dimension as periodic so a coordinate that leaves through one bound comes back
+	// in through the other. Use it for parameters such as angles. A dimension with only one bound can not be
+	// wrapped, so its coordinates are mirrored at the bound as with BoundaryReflect.
+	BoundaryWrap
+
+	// BoundaryResample replaces each coordinate that is outside its bounds with a random value inside the
+	// bounds drawn from Options.Source. A dimension with only one bound has no range to sample from, so its
+	// coordinates are mirrored at the bound as with BoundaryReflect.
+	BoundaryResample
+)
+
 // Options should be configured for your particular function and optimization problem.
 // The defaults configured in NewOptions should be considered a starting point that are
 // likely not well suited for your problem.
@@ -79,24 +223,179 @@ type Options struct {
 	// It is the threshold for the difference in objective function values between the best and worst points in the simplex.
 	// The algorithm terminates when this difference is less than or equal to Tolerance.
 	// A smaller Tolerance value leads to a more accurate solution but may require more iterations to converge.
+	// Tolerance is absolute so it should be chosen based on the magnitude of the objective function values.
+	// Tolerance may be 0 when RelativeTolerance is set.
 	Tolerance float64
 
+	// RelativeTolerance is a convergence criterion on the difference in objective function values relative to the
+	// magnitude of the best value. It is satisfied when
+	//
+	//	|F_worst - F_best| <= RelativeTolerance * max(|F_best|, tiny)
+	//
+	// where tiny is the smallest normal float64, so that a best value of 0 does not disable the criterion.
+	// The objective function value criterion is satisfied when either the Tolerance or the RelativeTolerance
+	// condition holds. Set Tolerance to 0 to only use the relative criterion. Use RelativeTolerance when the
+	// magnitude of the objective function is unknown or far from 1: an absolute Tolerance of 1e-6 is never
+	// reached by a function with values around 1e9 and is reached immediately by one with values around 1e-12.
+	// If RelativeTolerance is set to 0, the relative criterion is disabled.
+	RelativeTolerance float64
+
+	// XTolerance is the convergence criterion on the size of the simplex, similar to TolX in MATLAB's fminsearch
+	// and xatol in SciPy. It is the threshold for the largest distance between the best point and any other point
+	// in the simplex. If XTolerance is set to 0, the criterion is disabled.
+	// On flat plateaus the objective function values can be equal while the simplex is still large. On steep
+	// functions the objective function values may differ a lot long after the points have converged.
+	// Use Convergence to choose which criteria must be satisfied.
+	XTolerance float64
+
+	// Convergence selects which convergence criteria must be satisfied for the algorithm to terminate.
+	// By default both Tolerance and, when it is set, XTolerance must be satisfied.
+	Convergence ConvergenceCriteria
+
 	// CollapseThreshold is the threshold used to detect the collapse of the simplex in the Nelder-Mead algorithm.
 	// It is the minimum average edge length of the simplex below which the algorithm is considered to have collapsed and returns an error.
 	// A collapse may indicate that the optimization process is stuck in a degenerate region or that the chosen parameters (Alpha, Beta, Gamma, and Delta) are not suitable for the specific optimization problem.
 	// If CollapseThreshold is set to 0, the collapse detection feature is disabled.
 	CollapseThreshold float64
 
+	// Restart makes the optimization continue from a fresh simplex instead of stopping or stalling when the
+	// simplex degenerates. The new simplex is built around the best point by InitialSimplex, so it has the
+	// original step size, as in O'Neill's AS 47. The best point is kept and the other n vertices are evaluated
+	// again. Restarts count against MaxIterations, MaxEvaluations, and MaxDuration. A simplex that has
+	// converged is never rebuilt and stops with TerminationConverged, even when it has also collapsed. By
+	// default the simplex is never rebuilt.
+	Restart RestartPolicy
+
+	// MaxRestarts is the maximum number of times the simplex is rebuilt. Once it is reached, a collapse
+	// returns ErrorSimplexCollapse. If MaxRestarts is set to 0, the number of restarts is not limited.
+	MaxRestarts int
+
+	// StagnationIterations is the number of consecutive iterations without progress after which
+	// RestartOnStagnation rebuilds the simplex. An iteration makes progress when it lowers the best objective
+	// function value, or shrinks the spread or the size of the simplex below the smallest seen since the
+	// last restart, so a simplex that is closing in on a minimum is not restarted. A simplex that has
+	// converged is never restarted.
+	StagnationIterations int
+
 	// MaxIterations sets an upper bound on how long the algorithm should run to find the minima.
 	MaxIterations int
 
+	// MaxEvaluations sets an upper bound on the number of times the objective function is called.
+	// Unlike MaxIterations, it bounds the cost of the optimization: a reflection costs one or two evaluations
+	// while a shrink costs n. The bound is exact. When it is reached part way through an iteration, the
+	// optimization stops without calling the objective function again and returns the best point evaluated.
+	// If MaxEvaluations is set to 0, the number of evaluations is not limited.
+	MaxEvaluations int
+
+	// MaxDuration sets an upper bound on the wall-clock time the optimization may take. The elapsed time is
+	// checked between iterations and before each objective function evaluation. Once it exceeds MaxDuration,
+	// the optimization stops and returns the best point found so far. A single slow objective function call
+	// is not interrupted. If MaxDuration is set to 0, the duration is not limited.
+	MaxDuration time.Duration
+
+	// Now returns the current time. It is used to measure the elapsed time for MaxDuration and defaults to
+	// time.Now. Tests may set it to a fake clock to make the time budget deterministic. When Parallelism is
+	// greater than 1, Now must be safe for concurrent use.
+	Now func() time.Time
+
+	// Speculative makes each iteration evaluate the reflected, expanded, and both contracted points
+	// concurrently before deciding which one to use. An iteration then takes about as long as a single
+	// objective function call, at the cost of four evaluations per iteration instead of one or two. The
+	// sequence of points is the same as without Speculative, except that errors and non-finite values
+	// returned for candidates the standard algorithm would not have evaluated still stop the optimization
+	// according to ObjectiveErrors and NonFinite. The objective function and Now must be safe for
+	// concurrent use.
+	Speculative bool
+
+	// Parallelism is the maximum number of concurrent objective function calls used to evaluate batches of
+	// independent points: the vertices of the initial simplex and the vertices moved by a shrink. When
+	// Parallelism is greater than 1, the objective function must be safe for concurrent use. The results do
+	// not depend on the order the calls complete in. If Parallelism is 0 or 1, points are evaluated one at a time.
+	Parallelism int
+
 	// Constraints is an optional list of constraints for each dimension of the optimization problem. Each Constraint
 	// specifies a Min and Max value, which define the lower and upper bounds for the corresponding dimension.
 	// If Constraints is not provided or is empty, the optimization problem is considered unconstrained.
 	// Providing Constraints can help guide the optimization process and prevent the algorithm from exploring
 	// infeasible regions of the search space. The appropriate constraints should be chosen based on the problem's
 	// specific requirements and the characteristics of the objective function.
+	//
+	// Constraints are strict: the objective function is never called with an x outside the bounds, so it
+	// does not need to handle points outside its domain. Points the algorithm would place outside the bounds
+	// are moved inside as configured by BoundaryHandling before they are evaluated. The objective function is
+	// never called with a NaN or infinite coordinate either, which a dimension with an infinite bound could
+	// otherwise reach. Such points are given the value +Inf and are not counted as evaluations.
 	Constraints []Constraint
+
+	// BoundaryHandling configures how the reflected, expanded, and contracted points are moved back inside
+	// Constraints before they are evaluated, so the objective function is never called outside the bounds.
+	// By default the points are clamped to the bounds.
+	BoundaryHandling BoundaryHandling
+
+	// Transform replaces BoundaryHandling with a change of variables that maps an unconstrained internal
+	// space onto the Constraints box. The objective function, Observer, Result, and errors still receive and
+	// report points in the space of the objective function. InitialSimplex, XTolerance, CollapseThreshold,
+	// Result.Size, Result.EdgeLength, and IterationInfo.Diameter apply to the internal space, where the
+	// default initial step of 1 moves a coordinate by a sizable fraction of its range. ConstraintRangeSimplex
+	// can not be used with a Transform. Transform has no effect when Constraints is empty.
+	Transform Transform
+
+	// InequalityConstraints is an optional list of constraints g that a point x satisfies when g(x) <= 0.
+	// Unlike Constraints, they may couple dimensions, such as requiring x[0]+x[1] <= 1. They are enforced as
+	// configured by InequalityHandling by minimizing a merit function in place of the objective function, so
+	// Result.F, IterationInfo, and errors report the merit function value, which is the objective function
+	// value at points that satisfy the constraints. Result.ConstraintViolation reports how far the returned
+	// point is from satisfying them. A constraint returning NaN is treated as violated. The objective function
+	// is not called at such a point, which gets the merit function value +Inf without counting as an
+	// evaluation.
+	InequalityConstraints []func(x []float64) float64
+
+	// LinearEquality restricts the optimization to the points that satisfy linear equations A·x = B. x0 is
+	// projected onto the closest point that satisfies them, which must be inside Constraints. The simplex
+	// moves in the coordinates of the null space of A, so InitialSimplex, XTolerance, CollapseThreshold,
+	// and parallel vertices apply to those coordinates and a GivenSimplex must have one coordinate for each
+	// of them. Constraints are enforced by treating every point outside them as +Inf without calling the
+	// objective function or counting an evaluation, so BoundaryHandling and Transform have no effect. x0 may
+	// be on a bound, such as a corner of the simplex of mixture fractions.
+	LinearEquality LinearEquality
+
+	// InequalityHandling configures how InequalityConstraints are enforced. By default a static penalty
+	// weighted by PenaltyWeight is added to the objective function.
+	InequalityHandling InequalityHandling
+
+	// PenaltyWeight is the weight of the squared constraint violations used by InequalityPenalty and the
+	// initial weight used by InequalityAugmentedLagrangian. It must be greater than 0 when
+	// InequalityConstraints are set, unless InequalityHandling is InequalityBarrier.
+	PenaltyWeight float64
+
+	// ConstraintTolerance is the largest violation of InequalityConstraints that
+	// InequalityAugmentedLagrangian accepts before it stops updating the multipliers.
+	ConstraintTolerance float64
+
+	// Source is the source of random numbers used by BoundaryResample. When Source is nil, a source with a
+	// fixed seed is used so the optimization is reproducible. Speculative does not give the same sequence of
+	// points as the standard algorithm with BoundaryResample because it draws numbers for every candidate.
+	// A rand.Source is not safe for concurrent use, so optimizations running at the same time must not share
+	// one. MultiStart gives each optimization its own.
+	Source rand.Source
+
+	// Observer is an optional function called after each iteration. It can be used to log progress, record
+	// the path the simplex took, or implement additional stopping rules. When Observer returns true the
+	// optimization stops and the best point found so far is returned.
+	Observer func(IterationInfo) (stop bool)
+
+	// ObjectiveErrors configures what happens when the objective function passed to RunE returns an error.
+	// By default the optimization is stopped.
+	ObjectiveErrors ObjectiveErrorPolicy
+
+	// NonFinite configures how NaN and infinite values returned by the objective function are handled.
+	// By default NaN is treated as +Inf.
+	NonFinite NonFinitePolicy
+
+	// InitialSimplex configures how the simplex the optimization starts from is built around x0.
+	// The default offsets each coordinate of x0 by DefaultStep. Use RelativeSimplex or StepSimplex
+	// for problems where the coordinates have very different scales.
+	InitialSimplex InitialSimplex
 }
 
 // NewOptions should be considered a starting point that may not be suited for your optimization problem.
@@ -108,7 +407,35 @@ func NewOptions() Options {
 		Delta:         DefaultDelta,
 		Tolerance:     DefaultTolerance,
 		MaxIterations: DefaultMaxIterations,
+
+		PenaltyWeight:       DefaultPenaltyWeight,
+		ConstraintTolerance: DefaultConstraintTolerance,
+	}
+}
+
+// NewAdaptiveOptions returns options with the reflection, expansion, contraction, and shrink coefficients
+// adapted to the dimension n of the problem as proposed by Gao and Han in "Implementing the Nelder-Mead
+// simplex algorithm with adaptive parameters" (2012):
+//
+//	Alpha = 1
+//	Gamma = 1 + 2/n
+//	Beta  = 0.75 - 1/(2n)
+//	Delta = 1 - 1/n
+//
+// The standard coefficients tend to shrink the simplex too eagerly in higher dimensions. The adaptive
+// coefficients make expansion and shrinking less aggressive as n grows, which usually converges much faster
+// for problems with more than about 10 dimensions. For n <= 2 the coefficients are the same as NewOptions.
+func NewAdaptiveOptions(n int) Options {
+	options := NewOptions()
+	if n <= 2 {
+		return options
 	}
+	dimension := float64(n)
+	options.Alpha = 1
+	options.Gamma = 1 + 2/dimension
+	options.Beta = 0.75 - 1/(2*dimension)
+	options.Delta = 1 - 1/dimension
+	return options
 }
 
 func (options *Options) validate() error {
@@ -128,14 +455,100 @@ func (options *Options) validate() error {
 		return errors.New("invalid Options parameter: Delta must be in the range [0, 1]")
 	}
 
-	if options.Tolerance <= 0 {
+	if !(options.RelativeTolerance >= 0) {
+		return errors.New("invalid Options parameter: RelativeTolerance must not be negative")
+	}
+
+	if options.RelativeTolerance > 0 {
+		if !(options.Tolerance >= 0) {
+			return errors.New("invalid Options parameter: Tolerance must not be negative")
+		}
+	} else if !(options.Tolerance > 0) {
 		return errors.New("invalid Options parameter: Tolerance must be greater than 0")
 	}
 
+	if !(options.XTolerance >= 0) {
+		return errors.New("invalid Options parameter: XTolerance must not be negative")
+	}
+
+	switch options.Convergence {
+	case ConvergenceFAndX, ConvergenceF:
+	case ConvergenceX:
+		if options.XTolerance == 0 {
+			return errors.New("invalid Options parameter: XTolerance must be greater than 0 when Convergence is ConvergenceX")
+		}
+	default:
+		return errors.New("invalid Options parameter: Convergence must be ConvergenceFAndX, ConvergenceF, or ConvergenceX")
+	}
+
 	if options.MaxIterations <= 0 {
 		return errors.New("invalid Options parameter: MaxIterations must be greater than 0")
 	}
 
+	if options.MaxEvaluations < 0 {
+		return errors.New("invalid Options parameter: MaxEvaluations must not be negative")
+	}
+
+	if options.MaxDuration < 0 {
+		return errors.New("invalid Options parameter: MaxDuration must not be negative")
+	}
+
+	if options.Parallelism < 0 {
+		return errors.New("invalid Options parameter: Parallelism must not be negative")
+	}
+
+	if options.ObjectiveErrors != ObjectiveErrorAbort && options.ObjectiveErrors != ObjectiveErrorInfeasible {
+		return errors.New("invalid Options parameter: ObjectiveErrors must be ObjectiveErrorAbort or ObjectiveErrorInfeasible")
+	}
+
+	if options.NonFinite < NonFiniteAsInf || options.NonFinite > NonFiniteFail {
+		return errors.New("invalid Options parameter: NonFinite must be NonFiniteAsInf, NonFiniteReject, or NonFiniteFail")
+	}
+
+	if options.BoundaryHandling < BoundaryClamp || options.BoundaryHandling > BoundaryResample {
+		return errors.New("invalid Options parameter: BoundaryHandling must be BoundaryClamp, BoundaryReflect, BoundaryWrap, or BoundaryResample")
+	}
+
+	if options.Transform < TransformNone || options.Transform > TransformLogistic {
+		return errors.New("invalid Options parameter: Transform must be TransformNone, TransformSine, or TransformLogistic")
+	}
+
+	if err := options.LinearEquality.validate(); err != nil {
+		return err
+	}
+
+	if options.InequalityHandling < InequalityPenalty || options.InequalityHandling > InequalityBarrier {
+		return errors.New("invalid Options parameter: InequalityHandling must be InequalityPenalty, InequalityAugmentedLagrangian, or InequalityBarrier")
+	}
+
+	for _, g := range options.InequalityConstraints {
+		if g == nil {
+			return errors.New("invalid Options parameter: InequalityConstraints must not contain nil functions")
+		}
+	}
+	if len(options.InequalityConstraints) > 0 && options.InequalityHandling != InequalityBarrier && !(options.PenaltyWeight > 0) {
+		return errors.New("invalid Options parameter: PenaltyWeight must be greater than 0 when InequalityConstraints are set")
+	}
+
+	if !(options.ConstraintTolerance >= 0) {
+		return errors.New("invalid Options parameter: ConstraintTolerance must not be negative")
+	}
+
+	if options.Restart&^(RestartOnCollapse|RestartOnStagnation) != 0 {
+		return errors.New("invalid Options parameter: Restart must be a combination of RestartOnCollapse and RestartOnStagnation")
+	}
+
+	if options.MaxRestarts < 0 {
+		return errors.New("invalid Options parameter: MaxRestarts must not be negative")
+	}
+
+	if options.StagnationIterations < 0 {
+		return errors.New("invalid Options parameter: StagnationIterations must not be negative")
+	}
+	if options.Restart&RestartOnStagnation != 0 && options.StagnationIterations == 0 {
+		return errors.New("invalid Options parameter: StagnationIterations must be greater than 0 when Restart includes RestartOnStagnation")
+	}
+
 	for _, constraint := range options.Constraints {
 		err := constraint.validate()
 		if err != nil {
@@ -146,6 +559,13 @@ func (options *Options) validate() error {
 	return nil
 }
 
+// Constraint bounds a dimension to the range [Min, Max]. Set Min to math.Inf(-1) or Max to math.Inf(1)
+// for a dimension that is only bounded on one side, or both for a dimension that is not bounded at all.
+//
+// A Constraint with Min equal to Max fixes the dimension to that value. Only the other dimensions are
+// optimized, with a simplex that has one vertex per free dimension, and the objective function is still
+// called with every dimension. A fixed dimension does not count towards the size of the simplex built by
+// InitialSimplex, so GivenSimplex must only have the free dimensions.
 type Constraint struct {
 	Min, Max float64
 }
@@ -155,18 +575,14 @@ func (c *Constraint) validate() error {
 		return errors.New("constraint value for Min and Max must be valid numbers")
 	}
 
-	if math.IsInf(c.Min, 0) || math.IsInf(c.Max, 0) {
-		return errors.New("constraint value for Min and Max must not be infinite")
+	if math.IsInf(c.Min, 1) || math.IsInf(c.Max, -1) {
+		return errors.New("constraint value for Min must not be +Inf and Max must not be -Inf")
 	}
 
 	if c.Max < c.Min {
 		return errors.New("constraint value for Min must be less than Max")
 	}
 
-	if c.Min == c.Max {
-		return errors.New("constraint value for Min not be equal to Max")
-	}
-
 	return nil
 }
 
@@ -174,9 +590,12 @@ func (options *Options) validateX0(x0 []float64) error {
 	if len(options.Constraints) != 0 && len(options.Constraints) != len(x0) {
 		return errors.New("invalid options: The number of constraints must match the length of x0")
 	}
+	if len(options.LinearEquality.A) != 0 && len(options.LinearEquality.A[0]) != len(x0) {
+		return errors.New("invalid options: The number of LinearEquality coefficients must match the length of x0")
+	}
 	if len(options.Constraints) != 0 {
 		for i, x := range x0 {
-			if x < options.Constraints[i].Min || x > options.Constraints[i].Max {
+			if !(x >= options.Constraints[i].Min && x <= options.Constraints[i].Max) || math.IsInf(x, 0) {
 				return errors.New("invalid initial x parameter: x0 must satisfy the constraints")
 			}
 		}
@@ -188,7 +607,8 @@ func (options *Options) validateX0(x0 []float64) error {
 // function 'f', an initial guess 'x0', and an Options struct that configures the behavior of the algorithm.
 // Run returns the best solution found as a Point, containing the optimized input vector and the corresponding
 // value of the objective function. If an error occurs during the optimization process, such as a simplex
-// collapse or validation error, Run returns an error.
+// collapse or validation error, Run returns an error. When the simplex collapses, Run returns the best point
+// of the collapsed simplex along with an ErrorSimplexCollapse so results that are good enough can be used.
 //
 // The Nelder-Mead algorithm is a gradient-free optimization method that uses a simplex (a polytope with n+1
 // vertices in n-dimensional space) to explore the search space. The algorithm iteratively updates the simplex
@@ -200,104 +620,866 @@ func (options *Options) validateX0(x0 []float64) error {
 // The Run function is suitable for optimizing continuous, possibly non-convex, and noisy functions in
 // low to moderate dimensions. However, its performance may degrade as the dimensionality of the problem
 // increases or if the objective function has numerous local minima or sharp features.
+//
+// Use RunWithResult to find out why the optimization stopped and RunContext to stop it early.
 func Run(f Objective, x0 []float64, options Options) (Point, error) {
-	if err := options.validate(); err != nil {
+	return RunContext(context.Background(), f, x0, options)
+}
+
+// RunContext runs the same optimization as Run but stops early when ctx is done. The context is checked
+// between iterations and before each objective function evaluation. When ctx is done, RunContext returns
+// the best point found so far together with ctx.Err().
+func RunContext(ctx context.Context, f Objective, x0 []float64, options Options) (Point, error) {
+	result, err := RunE(ctx, objectiveE(f), x0, options)
+	return bestPoint(ctx, result, err)
+}
+
+// bestPoint returns the best point of result when err allows for it to be
+// used: when the context is done or the simplex collapsed.
+func bestPoint(ctx context.Context, result Result, err error) (Point, error) {
+	if err != nil && err != ctx.Err() && !errors.As(err, new(ErrorSimplexCollapse)) {
 		return Point{}, err
 	}
+	return result.Point, err
+}
+
+// RunWithResult runs the same optimization as Run but returns a Result describing the final state of the
+// optimization, including how many iterations and objective function evaluations it took and why it stopped.
+//
+// When the simplex collapses, the returned Result describes the simplex at the time of the collapse and the
+// error is ErrorSimplexCollapse.
+func RunWithResult(f Objective, x0 []float64, options Options) (Result, error) {
+	return RunE(context.Background(), objectiveE(f), x0, options)
+}
+
+// RunE is the most general way to run an optimization. It accepts an objective function that may fail,
+// stops early when ctx is done, and returns a Result describing the final state of the optimization.
+//
+// When the objective function returns an error and Options.ObjectiveErrors is ObjectiveErrorAbort, RunE
+// returns an ErrorObjective wrapping the error along with the best point found so far. When ctx is done,
+// RunE returns ctx.Err() along with the best point found so far.
+func RunE(ctx context.Context, f ObjectiveE, x0 []float64, options Options) (Result, error) {
+	return runE(ctx, f, x0, 1, options)
+}
+
+// runE validates its arguments and runs an optimization that moves
+// parallelVertices vertices in each iteration.
+func runE(ctx context.Context, f ObjectiveE, x0 []float64, parallelVertices int, options Options) (Result, error) {
+	if err := options.validate(); err != nil {
+		return Result{}, err
+	}
 	if err := options.validateX0(x0); err != nil {
-		return Point{}, err
+		return Result{}, err
+	}
+	if len(options.InequalityConstraints) > 0 {
+		return runInequality(ctx, f, x0, parallelVertices, options)
 	}
+	if len(options.LinearEquality.A) > 0 {
+		space, err := newNullSpace(options.LinearEquality, len(x0), options.Constraints)
+		if err != nil {
+			return Result{}, err
+		}
+		z0, options, err := space.internal(x0, options)
+		if err != nil {
+			return Result{}, err
+		}
+		parallelVertices = max(min(parallelVertices, len(z0)), 1)
+		return space.userSpace().run(ctx, space.barrier(f), z0, parallelVertices, options)
+	}
+	if space, ok := newReducedSpace(x0, options.Constraints); ok {
+		z0, options := space.internal(x0, options)
+		parallelVertices = max(min(parallelVertices, len(z0)), 1)
+		return space.userSpace().run(ctx, f, z0, parallelVertices, options)
+	}
+	if options.Transform != TransformNone && len(options.Constraints) > 0 {
+		space := searchSpace{transform: options.Transform, constraints: options.Constraints}
+		z0, options := space.internal(x0, options)
+		return space.userSpace().run(ctx, f, z0, parallelVertices, options)
+	}
+	return optimize(ctx, f, x0, parallelVertices, options)
+}
+
+func optimize(ctx context.Context, f ObjectiveE, x0 []float64, parallelVertices int, options Options) (Result, error) {
+	o, err := newOptimizer(ctx, f, x0, options)
+	if err != nil {
+		return Result{}, err
+	}
+	o.parallelVertices = parallelVertices
+	err = o.run()
+	return o.result(), err
+}
 
-	simplex := createSimplex(x0, len(x0), options.Constraints)
+// Result describes the outcome of an optimization.
+type Result struct {
+	// Point is the best point found.
+	Point
 
-	for i := 0; i < len(simplex.Points); i++ {
-		simplex.Points[i].F = f(simplex.Points[i].X)
+	// Iterations is the number of Nelder-Mead iterations (reflect, expand, contract, or shrink steps) taken.
+	Iterations int
+
+	// Evaluations is the number of times the objective function was called.
+	Evaluations int
+
+	// Simplex is the final simplex sorted from best to worst point.
+	Simplex Simplex
+
+	// Spread is the difference in objective function values between the worst and best points of the final simplex.
+	Spread float64
+
+	// EdgeLength is the average edge length of the final simplex.
+	EdgeLength float64
+
+	// Size is the largest distance between the best point and any other point of the final simplex.
+	// It is the value compared to Options.XTolerance.
+	Size float64
+
+	// Restarts is the number of times the simplex was rebuilt around the best point. See Options.Restart.
+	Restarts int
+
+	// ConstraintViolation is the largest value any of Options.InequalityConstraints returned for Point, or 0
+	// when Point satisfies all of them. Point.F includes the penalty for this violation.
+	ConstraintViolation float64
+
+	// Termination is the reason the optimization stopped.
+	Termination TerminationReason
+}
+
+// TerminationReason describes why an optimization stopped.
+type TerminationReason int
+
+const (
+	// TerminationConverged means the convergence criteria selected by Options.Convergence were satisfied.
+	TerminationConverged TerminationReason = iota + 1
+
+	// TerminationMaxIterations means Options.MaxIterations iterations ran without converging.
+	TerminationMaxIterations
+
+	// TerminationMaxEvaluations means the objective function was called Options.MaxEvaluations times
+	// without converging.
+	TerminationMaxEvaluations
+
+	// TerminationMaxDuration means the optimization ran for longer than Options.MaxDuration.
+	TerminationMaxDuration
+
+	// TerminationSimplexCollapse means the average edge length of the simplex fell below
+	// Options.CollapseThreshold.
+	TerminationSimplexCollapse
+
+	// TerminationCanceled means the context passed to RunContext was canceled or its deadline passed.
+	TerminationCanceled
+
+	// TerminationObserver means Options.Observer asked the optimization to stop.
+	TerminationObserver
+
+	// TerminationObjectiveError means the objective function returned an error.
+	TerminationObjectiveError
+
+	// TerminationNonFiniteObjective means the objective function returned NaN or an infinite value
+	// and Options.NonFinite is NonFiniteFail.
+	TerminationNonFiniteObjective
+)
+
+func (reason TerminationReason) String() string {
+	switch reason {
+	case TerminationConverged:
+		return "converged"
+	case TerminationMaxIterations:
+		return "max iterations"
+	case TerminationMaxEvaluations:
+		return "max evaluations"
+	case TerminationMaxDuration:
+		return "max duration"
+	case TerminationSimplexCollapse:
+		return "simplex collapse"
+	case TerminationCanceled:
+		return "canceled"
+	case TerminationObserver:
+		return "observer"
+	case TerminationObjectiveError:
+		return "objective error"
+	case TerminationNonFiniteObjective:
+		return "non-finite objective"
+	default:
+		return "unknown"
 	}
+}
+
+// Operation is the change made to the simplex during a single iteration.
+type Operation int
+
+const (
+	// OperationReflect replaced the worst point with its reflection through the centroid of the other points.
+	OperationReflect Operation = iota + 1
 
-	sortSimplex(simplex)
+	// OperationExpand replaced the worst point with the reflection of the reflected point through the centroid
+	// scaled by Gamma.
+	OperationExpand
 
+	// OperationContractOutside replaced the worst point with a point between the centroid and the reflected point.
+	OperationContractOutside
+
+	// OperationContractInside replaced the worst point with a point between the worst point and the centroid.
+	OperationContractInside
+
+	// OperationShrink moved every point except the best one towards the best point.
+	OperationShrink
+)
+
+func (operation Operation) String() string {
+	switch operation {
+	case OperationReflect:
+		return "reflect"
+	case OperationExpand:
+		return "expand"
+	case OperationContractOutside:
+		return "contract outside"
+	case OperationContractInside:
+		return "contract inside"
+	case OperationShrink:
+		return "shrink"
+	default:
+		return "unknown"
+	}
+}
+
+// IterationInfo describes the state of the optimization after an iteration. It is passed to Options.Observer.
+type IterationInfo struct {
+	// Iteration is the zero based index of the iteration.
+	Iteration int
+
+	// Operation is the change made to the simplex during the iteration.
+	Operation Operation
+
+	// Best is a copy of the best point in the simplex after the iteration.
+	Best Point
+
+	// Diameter is the largest distance between two points of the simplex after the iteration.
+	Diameter float64
+}
+
+func hasConverged(simplex Simplex, options Options) bool {
+	var (
+		spread     = simplex.spread()
+		fConverged = spread < options.Tolerance || spread <= options.RelativeTolerance*max(math.Abs(simplex.Points[0].F), minNormalFloat64)
+		xConverged = options.XTolerance > 0 && simplex.size() < options.XTolerance
+	)
+	switch options.Convergence {
+	case ConvergenceF:
+		return fConverged
+	case ConvergenceX:
+		return xConverged
+	default:
+		return fConverged && (options.XTolerance == 0 || xConverged)
+	}
+}
+
+// optimizer holds the state of a single optimization.
+type optimizer struct {
+	ctx     context.Context
+	f       ObjectiveE
+	options Options
+
+	simplex                                        Simplex
+	centroid                                       []float64
+	reflectedPoint, expandedPoint, contractedPoint Point
+	insideContractedPoint                          Point
+	candidates                                     []*Point
+
+	// parallelVertices is the number of vertices RunParallel moves in each
+	// iteration. The standard algorithm moves one.
+	parallelVertices int
+
+	iterations, evaluations int
+	termination             TerminationReason
+
+	// bestF, bestSpread, and bestSize are the lowest objective function
+	// value, spread, and size seen since the last restart and improved is
+	// the last iteration any of them decreased. They are used to detect
+	// stagnation.
+	bestF, bestSpread, bestSize float64
+	improved, restarts          int
+
+	now   func() time.Time
+	start time.Time
+
+	shrinkPoints, parallelPoints []*Point
+
+	// rnd is used by BoundaryResample.
+	rnd *rand.Rand
+}
+
+func newOptimizer(ctx context.Context, f ObjectiveE, x0 []float64, options Options) (*optimizer, error) {
+	simplex, err := buildSimplex(x0, options)
+	if err != nil {
+		return nil, err
+	}
 	var (
-		n               = len(x0)
-		pointBuf        = make([]float64, n*4, n*4)
-		reflectedPoint  = Point{X: pointBuf[:n:n]}
-		expandedPoint   = Point{X: pointBuf[n : n*2 : n*2]}
-		contractedPoint = Point{X: pointBuf[n*2 : n*3 : n*3]}
-		centroid        = pointBuf[n*3:]
+		n        = len(x0)
+		pointBuf = make([]float64, n*5, n*5)
+		now      = options.Now
 	)
-	done := false
-	for iter := 0; iter < options.MaxIterations && !done; iter++ {
-		done = runIteration(f, options, centroid, simplex, reflectedPoint, expandedPoint, contractedPoint)
-		if simplex.isCollapsed(options.CollapseThreshold) {
-			return Point{}, ErrorSimplexCollapse{}
+	if now == nil {
+		now = time.Now
+	}
+	o := &optimizer{
+		now:                   now,
+		start:                 now(),
+		ctx:                   ctx,
+		f:                     f,
+		options:               options,
+		simplex:               simplex,
+		reflectedPoint:        Point{X: pointBuf[:n:n]},
+		expandedPoint:         Point{X: pointBuf[n : n*2 : n*2]},
+		contractedPoint:       Point{X: pointBuf[n*2 : n*3 : n*3]},
+		insideContractedPoint: Point{X: pointBuf[n*3 : n*4 : n*4]},
+		centroid:              pointBuf[n*4:],
+	}
+	o.candidates = []*Point{&o.reflectedPoint, &o.expandedPoint, &o.contractedPoint, &o.insideContractedPoint}
+	if options.BoundaryHandling == BoundaryResample {
+		source := options.Source
+		if source == nil {
+			source = rand.NewSource(1)
 		}
+		o.rnd = rand.New(source)
 	}
-	return simplex.Points[0], nil
+	return o, nil
 }
 
-func runIteration(f Objective, options Options, centroid []float64, simplex Simplex, reflectedPoint, expandedPoint, contractedPoint Point) bool {
-	setZero(centroid)
-	lastPointIndex := len(simplex.Points) - 1
-	if math.Abs(simplex.Points[0].F-simplex.Points[lastPointIndex].F) < options.Tolerance {
-		return true
+// buildSimplex builds the simplex around x0 configured by
+// Options.InitialSimplex with every vertex inside the constraints.
+func buildSimplex(x0 []float64, options Options) (Simplex, error) {
+	initialSimplex := options.InitialSimplex
+	if initialSimplex == nil {
+		initialSimplex = StepSimplex(DefaultStep)
 	}
-	computeCentroid(centroid, simplex, lastPointIndex)
-	reflectedPoint = simplex.Points[lastPointIndex].reflect(reflectedPoint, f, centroid, options.Alpha)
-	if reflectedPoint.F < simplex.Points[len(simplex.Points)-2].F {
-		expandedPoint = reflectedPoint.reflect(expandedPoint, f, centroid, options.Gamma)
-		if expandedPoint.F < reflectedPoint.F {
-			simplex.replacePoint(lastPointIndex, expandedPoint)
-		} else {
-			simplex.replacePoint(lastPointIndex, reflectedPoint)
+	simplex, err := initialSimplex.initialSimplex(x0, options.Constraints)
+	if err != nil {
+		return Simplex{}, err
+	}
+	if len(options.Constraints) > 0 {
+		for i := 0; i < len(simplex.Points); i++ {
+			ensureXAreInConstraintBounds(simplex.Points[i].X, options.Constraints)
 		}
-	} else {
-		if reflectedPoint.F < simplex.Points[lastPointIndex].F {
-			simplex.replacePoint(lastPointIndex, reflectedPoint)
+	}
+	return simplex, nil
+}
+
+func (o *optimizer) run() error {
+	if err := o.evaluateSimplex(); err != nil {
+		return o.stop(err)
+	}
+	o.bestF, o.bestSpread, o.bestSize = math.Inf(1), math.Inf(1), math.Inf(1)
+	o.progress()
+	for {
+		switch {
+		case hasConverged(o.simplex, o.options):
+			o.termination = TerminationConverged
+			return nil
+		case o.iterations >= o.options.MaxIterations:
+			o.termination = TerminationMaxIterations
+			return nil
+		case o.options.MaxEvaluations > 0 && o.evaluations >= o.options.MaxEvaluations:
+			o.termination = TerminationMaxEvaluations
+			return nil
+		case o.outOfTime():
+			o.termination = TerminationMaxDuration
+			return nil
 		}
-		contractedPoint = simplex.Points[lastPointIndex].reflect(contractedPoint, f, centroid, options.Beta)
-		if contractedPoint.F < simplex.Points[lastPointIndex].F {
-			simplex.replacePoint(lastPointIndex, contractedPoint)
-		} else {
-			shrinkSimplex(simplex, options.Delta)
+		if err := o.ctx.Err(); err != nil {
+			return o.stop(err)
+		}
+		operation, err := o.iterate()
+		if err != nil {
+			return o.stop(err)
+		}
+		o.iterations++
+		if o.options.Observer != nil && o.options.Observer(o.iterationInfo(operation)) {
+			o.termination = TerminationObserver
+			return nil
+		}
+		o.progress()
+		if o.options.Restart != 0 && hasConverged(o.simplex, o.options) {
+			// A converged simplex is never restarted, even when it has also
+			// collapsed. The loop stops with TerminationConverged.
+			continue
+		}
+		collapsed := o.simplex.isCollapsed(o.options.CollapseThreshold)
+		if o.shouldRestart(collapsed) {
+			if err := o.restart(); err != nil {
+				return o.stop(err)
+			}
+			continue
+		}
+		if collapsed {
+			o.termination = TerminationSimplexCollapse
+			return ErrorSimplexCollapse{
+				Point:             o.simplex.Points[0],
+				Iteration:         o.iterations,
+				AverageEdgeLength: o.simplex.averageEdgeLength(),
+				Simplex:           o.simplex,
+			}
 		}
 	}
-	for i := 0; i < len(simplex.Points); i++ {
-		simplex.Points[i].F = f(simplex.Points[i].X)
+}
+
+// progress records the iteration as the last one that made progress when it
+// lowered the best objective function value, the spread, or the size of the
+// simplex.
+func (o *optimizer) progress() {
+	var (
+		best   = o.simplex.Points[0].F
+		spread = o.simplex.spread()
+		size   = o.simplex.size()
+	)
+	if best < o.bestF || spread < o.bestSpread || size < o.bestSize {
+		o.improved = o.iterations
 	}
-	sortSimplex(simplex)
-	if len(options.Constraints) > 0 {
-		ensureXAreInConstraintBounds(simplex.Points[0].X, options.Constraints)
+	// The spread is NaN while vertices are +Inf, which must not replace a
+	// recorded value.
+	if best < o.bestF {
+		o.bestF = best
+	}
+	if spread < o.bestSpread {
+		o.bestSpread = spread
+	}
+	if size < o.bestSize {
+		o.bestSize = size
 	}
-	return false
 }
 
-func createSimplex(x []float64, n int, constraints []Constraint) Simplex {
-	simplex := Simplex{Points: make([]Point, n+1)}
+// shouldRestart reports whether Options.Restart asks for the simplex to be
+// rebuilt.
+func (o *optimizer) shouldRestart(collapsed bool) bool {
+	options := o.options
+	if options.MaxRestarts > 0 && o.restarts >= options.MaxRestarts {
+		return false
+	}
+	stagnated := o.iterations-o.improved >= options.StagnationIterations
+	return collapsed && options.Restart&RestartOnCollapse != 0 ||
+		stagnated && options.Restart&RestartOnStagnation != 0
+}
 
-	for i := range simplex.Points {
-		simplex.Points[i].X = make([]float64, len(x))
+// restart rebuilds the simplex around the best point. The best point keeps
+// its objective function value and the other vertices are evaluated as a
+// batch.
+func (o *optimizer) restart() error {
+	simplex, err := buildSimplex(o.simplex.Points[0].X, o.options)
+	if err != nil {
+		return err
+	}
+	o.restarts++
+	o.bestF, o.bestSpread, o.bestSize = math.Inf(1), math.Inf(1), math.Inf(1)
+	points := o.simplex.Points
+	for i := 1; i < len(points); i++ {
+		copy(points[i].X, simplex.Points[i].X)
+		points[i].F = math.Inf(1)
 	}
+	moved := o.simplex.pointers()[1:]
+	if _, err := o.evaluateBatch(moved); err != nil {
+		for _, p := range moved {
+			_ = o.reject(p, nil)
+		}
+		return err
+	}
+	for _, p := range moved {
+		if err := o.reject(p, points[0].X); err != nil {
+			return err
+		}
+	}
+	sortSimplex(o.simplex)
+	o.progress()
+	return nil
+}
+
+// stop records why the optimization ended early. An iteration may have been
+// interrupted part way through, so the simplex is sorted again to make sure
+// the best point found so far is first.
+func (o *optimizer) stop(err error) error {
+	switch {
+	case err == errMaxEvaluations:
+		o.termination = TerminationMaxEvaluations
+		err = nil
+	case err == errMaxDuration:
+		o.termination = TerminationMaxDuration
+		err = nil
+	case err == o.ctx.Err():
+		o.termination = TerminationCanceled
+	case errors.As(err, new(ErrorObjective)):
+		o.termination = TerminationObjectiveError
+	case errors.As(err, new(ErrorNonFiniteObjective)):
+		o.termination = TerminationNonFiniteObjective
+	}
+	sortSimplex(o.simplex)
+	return err
+}
+
+// errMaxEvaluations and errMaxDuration stop an iteration when
+// Options.MaxEvaluations or Options.MaxDuration is reached. They are not
+// returned to callers.
+var (
+	errMaxEvaluations = errors.New("maximum number of evaluations reached")
+	errMaxDuration    = errors.New("maximum duration reached")
+)
+
+func (o *optimizer) outOfTime() bool {
+	return o.options.MaxDuration > 0 && o.now().Sub(o.start) >= o.options.MaxDuration
+}
+
+func (o *optimizer) iterationInfo(operation Operation) IterationInfo {
+	best := o.simplex.Points[0]
+	return IterationInfo{
+		Iteration: o.iterations - 1,
+		Operation: operation,
+		Best:      Point{X: slices.Clone(best.X), F: best.F},
+		Diameter:  o.simplex.diameter(),
+	}
+}
+
+func (o *optimizer) result() Result {
+	return Result{
+		Point:       o.simplex.Points[0],
+		Iterations:  o.iterations,
+		Evaluations: o.evaluations,
+		Simplex:     o.simplex,
+		Spread:      o.simplex.spread(),
+		EdgeLength:  o.simplex.averageEdgeLength(),
+		Size:        o.simplex.size(),
+		Restarts:    o.restarts,
+		Termination: o.termination,
+	}
+}
+
+// evaluate sets p.F to the value of the objective function at p.X.
+func (o *optimizer) evaluate(p *Point) error {
+	if o.options.MaxEvaluations > 0 && o.evaluations >= o.options.MaxEvaluations {
+		return errMaxEvaluations
+	}
+	if err := o.interrupted(); err != nil {
+		return err
+	}
+	o.feasible(p.X)
+	y, err := o.call(p.X)
+	if err == errInfeasible {
+		p.F = math.Inf(1)
+		return nil
+	}
+	o.evaluations++
+	return o.record(p, y, err)
+}
+
+// errInfeasible is returned in place of an objective function value for a
+// point that is rejected without calling the objective function. The point
+// is given the value +Inf and is not counted as an evaluation.
+var errInfeasible = errors.New("infeasible point")
+
+// call calls the objective function unless a coordinate of x is NaN or
+// infinite, which can happen when a bound is infinite. It is safe to call
+// from multiple goroutines.
+func (o *optimizer) call(x []float64) (float64, error) {
+	if !isFinite(x) {
+		return 0, errInfeasible
+	}
+	return o.f(x)
+}
 
-	for j := 0; j < n; j++ {
-		simplex.Points[0].X[j] = x[j]
+// isFinite reports whether every coordinate of x is finite.
+func isFinite(x []float64) bool {
+	for _, xi := range x {
+		if math.IsNaN(xi) || math.IsInf(xi, 0) {
+			return false
+		}
+	}
+	return true
+}
+
+// feasible guarantees x is inside the constraints right before it is
+// evaluated. Candidates are already moved inside by bound and the other
+// points are combinations of vertices, so it only corrects rounding errors.
+func (o *optimizer) feasible(x []float64) {
+	if len(o.options.Constraints) > 0 {
+		ensureXAreInConstraintBounds(x, o.options.Constraints)
 	}
+}
+
+// interrupted returns an error when the optimization must stop before the
+// next objective function call. It is safe to call from multiple goroutines.
+func (o *optimizer) interrupted() error {
+	if err := o.ctx.Err(); err != nil {
+		return err
+	}
+	if o.outOfTime() {
+		return errMaxDuration
+	}
+	return nil
+}
+
+// record sets p.F to the value y returned by the objective function applying
+// Options.ObjectiveErrors and Options.NonFinite.
+func (o *optimizer) record(p *Point, y float64, err error) error {
+	if err != nil {
+		if o.options.ObjectiveErrors != ObjectiveErrorInfeasible {
+			return ErrorObjective{X: slices.Clone(p.X), Iteration: o.iterations, Err: err}
+		}
+		y = math.Inf(1)
+	} else if math.IsNaN(y) || math.IsInf(y, 0) {
+		switch o.options.NonFinite {
+		case NonFiniteAsInf:
+			if math.IsNaN(y) {
+				y = math.Inf(1)
+			}
+		case NonFiniteFail:
+			return ErrorNonFiniteObjective{X: slices.Clone(p.X), F: y, Iteration: o.iterations}
+		}
+	}
+	p.F = y
+	return nil
+}
 
-	for i := 1; i <= n; i++ {
-		for j := 0; j < n; j++ {
-			if i-1 == j {
-				simplex.Points[i].X[j] = x[j] + 1.0
+// evaluateSimplex evaluates the initial simplex. Vertices that have not been
+// evaluated when the optimization is stopped are left at +Inf.
+func (o *optimizer) evaluateSimplex() error {
+	points := o.simplex.Points
+	for i := range points {
+		points[i].F = math.Inf(1)
+	}
+	if _, err := o.evaluateBatch(o.simplex.pointers()); err != nil {
+		for i := range points {
+			_ = o.reject(&points[i], nil)
+		}
+		return err
+	}
+	var (
+		anchor  []float64
+		anchorF = math.Inf(1)
+	)
+	for i := range points {
+		if !math.IsNaN(points[i].F) && (anchor == nil || points[i].F < anchorF) {
+			anchor, anchorF = points[i].X, points[i].F
+		}
+	}
+	// The anchor is the best point with a valid objective value. It is not
+	// moved by reject so it can be shared by every rejected vertex.
+	for i := range points {
+		if err := o.reject(&points[i], anchor); err != nil {
+			return err
+		}
+	}
+	sortSimplex(o.simplex)
+	return nil
+}
+
+// reject makes sure the objective value of a vertex is not NaN. When
+// Options.NonFinite is NonFiniteReject, a vertex where the objective value is
+// NaN is moved towards anchor and evaluated again. A vertex that is still NaN
+// after maxRejections attempts, or when anchor is nil, is given the value +Inf.
+func (o *optimizer) reject(p *Point, anchor []float64) error {
+	for i := 0; math.IsNaN(p.F) && anchor != nil && i < maxRejections; i++ {
+		for j := range p.X {
+			p.X[j] = anchor[j] + o.options.Delta*(p.X[j]-anchor[j])
+		}
+		if err := o.evaluate(p); err != nil {
+			p.F = math.Inf(1)
+			return err
+		}
+	}
+	if math.IsNaN(p.F) {
+		p.F = math.Inf(1)
+	}
+	return nil
+}
+
+// iterate performs a single Nelder-Mead iteration and returns the operation
+// that was applied to the simplex.
+func (o *optimizer) iterate() (Operation, error) {
+	step := o.step
+	if o.parallelVertices > 1 {
+		step = o.stepParallel
+	}
+	operation, err := step()
+	if err != nil {
+		return 0, err
+	}
+	if operation == OperationShrink {
+		if err := o.shrink(); err != nil {
+			return 0, err
+		}
+	}
+	// Only the vertices that moved have been re-evaluated, so the F values
+	// carried by the simplex are all current at this point.
+	sortSimplex(o.simplex)
+	return operation, nil
+}
+
+// step replaces the worst vertex with a better point and returns the
+// operation that produced it. It returns OperationShrink when the
+// contraction did not improve on the worst vertex, which may already have
+// been replaced with the reflected point.
+func (o *optimizer) step() (Operation, error) {
+	var (
+		simplex        = o.simplex
+		options        = o.options
+		lastPointIndex = len(simplex.Points) - 1
+		bestPoint      = &simplex.Points[0]
+		secondWorst    = &simplex.Points[max(lastPointIndex-1, 0)]
+		worstPoint     = &simplex.Points[lastPointIndex]
+	)
+	setZero(o.centroid)
+	computeCentroid(o.centroid, simplex, lastPointIndex)
+	if options.Speculative {
+		if err := o.evaluateCandidates(worstPoint); err != nil {
+			if o.reflectedPoint.F < bestPoint.F {
+				simplex.replacePoint(lastPointIndex, o.reflectedPoint)
+			}
+			return 0, err
+		}
+	} else if err := o.candidate(&o.reflectedPoint, worstPoint, options.Alpha); err != nil {
+		return 0, err
+	}
+	operation := OperationReflect
+	switch {
+	case o.reflectedPoint.F < secondWorst.F:
+		if err := o.candidate(&o.expandedPoint, &o.reflectedPoint, options.Gamma); err != nil {
+			// The reflected point is better than the worst vertex so it is
+			// kept even though the iteration could not be completed.
+			simplex.replacePoint(lastPointIndex, o.reflectedPoint)
+			return 0, err
+		}
+		if o.expandedPoint.F < o.reflectedPoint.F {
+			operation = OperationExpand
+			simplex.replacePoint(lastPointIndex, o.expandedPoint)
+		} else {
+			simplex.replacePoint(lastPointIndex, o.reflectedPoint)
+		}
+	case o.reflectedPoint.F < worstPoint.F:
+		// The reflected point replaces the worst vertex and the contraction
+		// is taken from it.
+		if err := o.candidate(&o.insideContractedPoint, &o.reflectedPoint, options.Beta); err != nil {
+			simplex.replacePoint(lastPointIndex, o.reflectedPoint)
+			return 0, err
+		}
+		if o.insideContractedPoint.F < o.reflectedPoint.F {
+			operation = OperationContractInside
+			simplex.replacePoint(lastPointIndex, o.insideContractedPoint)
+		} else {
+			operation = OperationShrink
+			simplex.replacePoint(lastPointIndex, o.reflectedPoint)
+		}
+	default:
+		if err := o.candidate(&o.contractedPoint, worstPoint, options.Beta); err != nil {
+			return 0, err
+		}
+		if o.contractedPoint.F < worstPoint.F {
+			operation = OperationContractOutside
+			simplex.replacePoint(lastPointIndex, o.contractedPoint)
+		} else {
+			operation = OperationShrink
+		}
+	}
+	return operation, nil
+}
+
+// candidate sets p to the reflection of from through the centroid scaled by
+// coefficient and evaluates it. In speculative mode every candidate has
+// already been evaluated by evaluateCandidates.
+func (o *optimizer) candidate(p, from *Point, coefficient float64) error {
+	if o.options.Speculative {
+		return nil
+	}
+	o.move(p.X, from, coefficient)
+	return o.evaluate(p)
+}
+
+// move sets x to the reflection of from through the centroid scaled by
+// coefficient and moves it inside the constraints.
+func (o *optimizer) move(x []float64, from *Point, coefficient float64) {
+	from.reflect(x, o.centroid, coefficient)
+	o.bound(x)
+}
+
+// bound moves x inside the constraints as configured by
+// Options.BoundaryHandling.
+func (o *optimizer) bound(x []float64) {
+	constraints := o.options.Constraints
+	if len(constraints) == 0 {
+		return
+	}
+	if o.options.BoundaryHandling == BoundaryClamp {
+		ensureXAreInConstraintBounds(x, constraints)
+		return
+	}
+	for i, c := range constraints {
+		if x[i] >= c.Min && x[i] <= c.Max {
+			continue
+		}
+		width := c.Max - c.Min
+		switch boundaryHandling := o.options.BoundaryHandling; {
+		case math.IsInf(width, 0):
+			// A dimension bounded on one side has no range to wrap around
+			// or sample from so the point is mirrored at the bound it
+			// crossed, which always lands inside.
+			if x[i] < c.Min {
+				x[i] = 2*c.Min - x[i]
 			} else {
-				simplex.Points[i].X[j] = x[j]
+				x[i] = 2*c.Max - x[i]
 			}
+		case boundaryHandling == BoundaryReflect:
+			// Mirroring at both bounds repeats every two widths.
+			y := math.Mod(x[i]-c.Min, 2*width)
+			if y < 0 {
+				y += 2 * width
+			}
+			if y > width {
+				y = 2*width - y
+			}
+			x[i] = c.Min + y
+		case boundaryHandling == BoundaryWrap:
+			y := math.Mod(x[i]-c.Min, width)
+			if y < 0 {
+				y += width
+			}
+			x[i] = c.Min + y
+		case boundaryHandling == BoundaryResample:
+			x[i] = c.Min + o.rnd.Float64()*width
 		}
+		// Rounding can leave the result just outside the bounds.
+		x[i] = min(max(x[i], c.Min), c.Max)
 	}
+}
 
-	if len(constraints) > 0 {
-		for i := 0; i < len(simplex.Points); i++ {
-			ensureXAreInConstraintBounds(simplex.Points[i].X, constraints)
+// shrink moves every vertex except the best one towards the best vertex. The
+// moved vertices are evaluated as a batch and each vertex is only replaced
+// after it has been evaluated so the simplex stays consistent if the
+// optimization is stopped part way through.
+func (o *optimizer) shrink() error {
+	var (
+		simplex   = o.simplex
+		delta     = o.options.Delta
+		bestPoint = simplex.Points[0]
+		moved     = o.shrinkBuffer()
+	)
+	for i := 1; i < len(simplex.Points); i++ {
+		for j := 0; j < len(simplex.Points[i].X); j++ {
+			moved[i-1].X[j] = bestPoint.X[j] + delta*(simplex.Points[i].X[j]-bestPoint.X[j])
 		}
 	}
+	evaluated, err := o.evaluateBatch(moved)
+	for i, p := range moved[:evaluated] {
+		if rejectErr := o.reject(p, bestPoint.X); rejectErr != nil && err == nil {
+			err = rejectErr
+		}
+		simplex.replacePoint(i+1, *p)
+	}
+	return err
+}
 
-	return simplex
+// shrinkBuffer returns scratch points for the n vertices moved by a shrink.
+func (o *optimizer) shrinkBuffer() []*Point {
+	if o.shrinkPoints == nil {
+		n := len(o.simplex.Points) - 1
+		buf := newSimplex(make([]float64, n))
+		o.shrinkPoints = buf.pointers()[:n]
+	}
+	return o.shrinkPoints
 }
 
 func sortSimplex(simplex Simplex) {
@@ -320,40 +1502,93 @@ func computeCentroid(centroid []float64, simplex Simplex, excludeIndex int) {
 	}
 }
 
-func shrinkSimplex(simplex Simplex, delta float64) {
-	bestPoint := simplex.Points[0]
-	for i := 1; i < len(simplex.Points); i++ {
-		for j := 0; j < len(simplex.Points[i].X); j++ {
-			simplex.Points[i].X[j] = bestPoint.X[j] + delta*(simplex.Points[i].X[j]-bestPoint.X[j])
-		}
-	}
-}
-
-func (p *Point) reflect(reflectedPoint Point, f Objective, centroid []float64, alpha float64) Point {
+// reflect sets x to the reflection of p through the centroid scaled by alpha.
+func (p *Point) reflect(x []float64, centroid []float64, alpha float64) {
 	for j := 0; j < len(p.X); j++ {
-		reflectedPoint.X[j] = centroid[j] + alpha*(centroid[j]-p.X[j])
+		x[j] = centroid[j] + alpha*(centroid[j]-p.X[j])
 	}
-	reflectedPoint.F = f(reflectedPoint.X)
-	return reflectedPoint
 }
 
-func ensureXAreInConstraintBounds(x []float64, constraints []Constraint) {
+// ensureXAreInConstraintBounds clamps x into the constraint bounds and reports
+// whether any value was changed.
+func ensureXAreInConstraintBounds(x []float64, constraints []Constraint) bool {
+	changed := false
 	for i := range x {
 		if x[i] < constraints[i].Min {
 			x[i] = constraints[i].Min
+			changed = true
 		}
 		if x[i] > constraints[i].Max {
 			x[i] = constraints[i].Max
+			changed = true
 		}
 	}
+	return changed
 }
 
-type ErrorSimplexCollapse struct{}
+// ErrorSimplexCollapse is returned when the average edge length of the simplex falls below
+// Options.CollapseThreshold. The simplex often already holds a good point when it collapses, so the error
+// describes the simplex at the time of the collapse. Run and RunContext also return the best point along
+// with the error.
+type ErrorSimplexCollapse struct {
+	// Point is the best point of the collapsed simplex.
+	Point Point
+
+	// Iteration is the number of iterations completed before the simplex collapsed.
+	Iteration int
+
+	// AverageEdgeLength is the average edge length of the collapsed simplex.
+	AverageEdgeLength float64
+
+	// Simplex is the collapsed simplex sorted from best to worst point.
+	Simplex Simplex
+}
 
 func (ErrorSimplexCollapse) Error() string { return "simplex has collapsed" }
 
+// ErrorObjective is returned when the objective function passed to RunE fails.
+type ErrorObjective struct {
+	// X is a copy of the point where the objective function failed.
+	X []float64
+
+	// Iteration is the number of iterations completed before the objective function failed.
+	Iteration int
+
+	// Err is the error returned by the objective function.
+	Err error
+}
+
+func (e ErrorObjective) Error() string {
+	return fmt.Sprintf("objective function failed at x = %v after %d iterations: %v", e.X, e.Iteration, e.Err)
+}
+
+func (e ErrorObjective) Unwrap() error { return e.Err }
+
+// ErrorNonFiniteObjective is returned when the objective function returns NaN or an infinite value and
+// Options.NonFinite is NonFiniteFail.
+type ErrorNonFiniteObjective struct {
+	// X is a copy of the point where the objective function was evaluated.
+	X []float64
+
+	// F is the value returned by the objective function.
+	F float64
+
+	// Iteration is the number of iterations completed before the value was returned.
+	Iteration int
+}
+
+func (e ErrorNonFiniteObjective) Error() string {
+	return fmt.Sprintf("objective function returned %v at x = %v after %d iterations", e.F, e.X, e.Iteration)
+}
+
+// averageEdgeLength returns the average distance between two points of the
+// simplex, or 0 for a simplex with a single point, which is the case when no
+// dimension is free.
 func (s *Simplex) averageEdgeLength() float64 {
 	n := len(s.Points)
+	if n < 2 {
+		return 0
+	}
 	totalLength := 0.0
 	count := 0
 
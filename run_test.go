@@ -1,10 +1,15 @@
 package neldermead
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"math"
 	"math/rand"
+	"slices"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestRun(t *testing.T) {
@@ -89,6 +94,696 @@ func TestRun(t *testing.T) {
 	})
 }
 
+func TestRunWithResult(t *testing.T) {
+	rosenbrock := func(x []float64) float64 {
+		return math.Pow(1-x[0], 2) + 100*math.Pow(x[1]-x[0]*x[0], 2)
+	}
+
+	t.Run("converged", func(t *testing.T) {
+		evaluations := 0
+		objective := func(x []float64) float64 {
+			evaluations++
+			return rosenbrock(x)
+		}
+
+		result, err := RunWithResult(objective, []float64{-1, -1}, NewOptions())
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if result.Termination != TerminationConverged {
+			t.Errorf("expected termination %q got %q", TerminationConverged, result.Termination)
+		}
+		if result.Evaluations != evaluations {
+			t.Errorf("expected %d evaluations got %d", evaluations, result.Evaluations)
+		}
+		if result.Iterations <= 0 || result.Iterations >= DefaultMaxIterations {
+			t.Errorf("unexpected iteration count %d", result.Iterations)
+		}
+		if result.Spread >= DefaultTolerance {
+			t.Errorf("expected spread below tolerance got %g", result.Spread)
+		}
+		if result.EdgeLength <= 0 {
+			t.Errorf("expected positive edge length got %g", result.EdgeLength)
+		}
+		if len(result.Simplex.Points) != 3 {
+			t.Fatalf("expected 3 points in the final simplex got %d", len(result.Simplex.Points))
+		}
+		if result.Simplex.Points[0].F != result.F {
+			t.Errorf("expected the best point to be the first point of the simplex")
+		}
+	})
+
+	t.Run("max iterations", func(t *testing.T) {
+		options := NewOptions()
+		options.MaxIterations = 5
+
+		result, err := RunWithResult(rosenbrock, []float64{-1, -1}, options)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if result.Termination != TerminationMaxIterations {
+			t.Errorf("expected termination %q got %q", TerminationMaxIterations, result.Termination)
+		}
+		if result.Iterations != options.MaxIterations {
+			t.Errorf("expected %d iterations got %d", options.MaxIterations, result.Iterations)
+		}
+	})
+
+	t.Run("invalid options", func(t *testing.T) {
+		_, err := RunWithResult(rosenbrock, []float64{-1, -1}, Options{})
+		if err == nil {
+			t.Errorf("expected error not nil")
+		}
+	})
+}
+
+func TestRunContext(t *testing.T) {
+	rosenbrock := func(x []float64) float64 {
+		return math.Pow(1-x[0], 2) + 100*math.Pow(x[1]-x[0]*x[0], 2)
+	}
+
+	t.Run("canceled during an iteration", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		evaluations := 0
+		best := math.Inf(1)
+		objective := func(x []float64) float64 {
+			evaluations++
+			if evaluations == 50 {
+				cancel()
+			}
+			y := rosenbrock(x)
+			best = min(best, y)
+			return y
+		}
+
+		point, err := RunContext(ctx, objective, []float64{-1, -1}, NewOptions())
+		if err != context.Canceled {
+			t.Fatalf("expected error %v got %v", context.Canceled, err)
+		}
+		if evaluations != 50 {
+			t.Errorf("expected the objective not to be called after cancel got %d evaluations", evaluations)
+		}
+		if len(point.X) != 2 {
+			t.Fatalf("expected the best point found so far got %v", point)
+		}
+		if point.F != best {
+			t.Errorf("expected best point F = %g got %g", best, point.F)
+		}
+	})
+
+	t.Run("deadline exceeded", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 0)
+		defer cancel()
+
+		result, err := RunE(ctx, objectiveE(rosenbrock), []float64{-1, -1}, NewOptions())
+		if err != context.DeadlineExceeded {
+			t.Fatalf("expected error %v got %v", context.DeadlineExceeded, err)
+		}
+		if result.Evaluations != 0 {
+			t.Errorf("expected no evaluations got %d", result.Evaluations)
+		}
+		if result.Termination != TerminationCanceled {
+			t.Errorf("expected termination %q got %q", TerminationCanceled, result.Termination)
+		}
+	})
+
+	t.Run("not canceled", func(t *testing.T) {
+		point, err := RunContext(context.Background(), rosenbrock, []float64{-1, -1}, NewOptions())
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		expectPoint(t, Point{F: 0, X: []float64{1, 1}}, point, 2)
+	})
+}
+
+func TestOptions_Observer(t *testing.T) {
+	rosenbrock := func(x []float64) float64 {
+		return math.Pow(1-x[0], 2) + 100*math.Pow(x[1]-x[0]*x[0], 2)
+	}
+
+	t.Run("reports every iteration", func(t *testing.T) {
+		var infos []IterationInfo
+		options := NewOptions()
+		options.Observer = func(info IterationInfo) bool {
+			infos = append(infos, info)
+			return false
+		}
+
+		result, err := RunWithResult(rosenbrock, []float64{-1, -1}, options)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if len(infos) != result.Iterations {
+			t.Fatalf("expected %d observations got %d", result.Iterations, len(infos))
+		}
+		operations := make(map[Operation]int)
+		for i, info := range infos {
+			if info.Iteration != i {
+				t.Errorf("expected iteration index %d got %d", i, info.Iteration)
+			}
+			if info.Diameter <= 0 {
+				t.Errorf("iteration %d: expected positive diameter got %g", i, info.Diameter)
+			}
+			if i > 0 && info.Best.F > infos[i-1].Best.F {
+				t.Errorf("iteration %d: best point got worse", i)
+			}
+			operations[info.Operation]++
+		}
+		for _, operation := range []Operation{OperationReflect, OperationContractOutside, OperationContractInside} {
+			if operations[operation] == 0 {
+				t.Errorf("expected at least one %s operation", operation)
+			}
+		}
+		last := infos[len(infos)-1].Best
+		if last.F != result.F || &last.X[0] == &result.X[0] {
+			t.Errorf("expected the last observation to be a copy of the best point")
+		}
+	})
+
+	t.Run("stop", func(t *testing.T) {
+		options := NewOptions()
+		options.Observer = func(info IterationInfo) bool {
+			return info.Iteration == 9
+		}
+
+		result, err := RunWithResult(rosenbrock, []float64{-1, -1}, options)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if result.Termination != TerminationObserver {
+			t.Errorf("expected termination %q got %q", TerminationObserver, result.Termination)
+		}
+		if result.Iterations != 10 {
+			t.Errorf("expected 10 iterations got %d", result.Iterations)
+		}
+	})
+}
+
+func TestOptimizer_iterate(t *testing.T) {
+	for _, tt := range []struct {
+		name      string
+		objective Objective
+		x0        []float64
+		want      Operation
+	}{
+		{name: "reflect", objective: func(x []float64) float64 { return x[0] + 2*math.Max(x[1], 0) + 0.5*math.Min(x[1], 0) }, x0: []float64{0, 0}, want: OperationReflect},
+		{name: "expand", objective: func(x []float64) float64 { return math.Min(-x[0], 5*x[0]) }, x0: []float64{0}, want: OperationExpand},
+		{name: "contract outside", objective: func(x []float64) float64 { return math.Max(x[0], -5*x[0]-3) }, x0: []float64{0}, want: OperationContractOutside},
+		{name: "contract inside", objective: func(x []float64) float64 { return math.Max(x[0]-0.3, 0.3-0.2*x[0]) }, x0: []float64{0}, want: OperationContractInside},
+		{name: "shrink", objective: func(x []float64) float64 { return math.Min(1, math.Abs(x[0])*1e9) }, x0: []float64{0}, want: OperationShrink},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			o, err := newOptimizer(context.Background(), objectiveE(tt.objective), tt.x0, NewOptions())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := o.evaluateSimplex(); err != nil {
+				t.Fatal(err)
+			}
+			got, err := o.iterate()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("expected operation %s got %s", tt.want, got)
+			}
+		})
+	}
+}
+
+// TestOptimizer_step locks in the points each branch of an iteration
+// evaluates and the vertex it keeps. The simplex is (0, 0), (1, 0), and
+// (0, 1) with the worst vertex last, so the centroid of the other vertices is
+// (0.5, 0) and the candidates are:
+//
+//	reflected          (1, -1)      centroid + Alpha*(centroid-worst)
+//	expanded           (-0.5, 2)    centroid + Gamma*(centroid-reflected)
+//	contracted outside (0.75, -0.5) centroid + Beta*(centroid-worst)
+//	contracted inside  (0.25, 0.5)  centroid + Beta*(centroid-reflected)
+func TestOptimizer_step(t *testing.T) {
+	var (
+		worst             = [2]float64{0, 1}
+		reflected         = [2]float64{1, -1}
+		expanded          = [2]float64{-0.5, 2}
+		contractedOutside = [2]float64{0.75, -0.5}
+		contractedInside  = [2]float64{0.25, 0.5}
+	)
+	for _, tt := range []struct {
+		name      string
+		values    map[[2]float64]float64
+		want      Operation
+		evaluated [][2]float64
+		kept      [2]float64
+	}{
+		{
+			name:      "expand when the reflected point is better than the second worst vertex",
+			values:    map[[2]float64]float64{reflected: 0.5, expanded: 0.2},
+			want:      OperationExpand,
+			evaluated: [][2]float64{reflected, expanded},
+			kept:      expanded,
+		},
+		{
+			name:      "keep the reflected point when expanding does not improve on it",
+			values:    map[[2]float64]float64{reflected: 0.5, expanded: 0.8},
+			want:      OperationReflect,
+			evaluated: [][2]float64{reflected, expanded},
+			kept:      reflected,
+		},
+		{
+			name:      "contract inside when the reflected point is only better than the worst vertex",
+			values:    map[[2]float64]float64{reflected: 1.5, contractedInside: 1.2},
+			want:      OperationContractInside,
+			evaluated: [][2]float64{reflected, contractedInside},
+			kept:      contractedInside,
+		},
+		{
+			name:      "shrink with the reflected point when the inside contraction is not better than it",
+			values:    map[[2]float64]float64{reflected: 1.5, contractedInside: 1.8},
+			want:      OperationShrink,
+			evaluated: [][2]float64{reflected, contractedInside},
+			kept:      reflected,
+		},
+		{
+			name:      "contract outside when the reflected point is not better than any vertex",
+			values:    map[[2]float64]float64{reflected: 3, contractedOutside: 1.5},
+			want:      OperationContractOutside,
+			evaluated: [][2]float64{reflected, contractedOutside},
+			kept:      contractedOutside,
+		},
+		{
+			name:      "shrink when the outside contraction is not better than the worst vertex",
+			values:    map[[2]float64]float64{reflected: 3, contractedOutside: 2.5},
+			want:      OperationShrink,
+			evaluated: [][2]float64{reflected, contractedOutside},
+			kept:      worst,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			values := map[[2]float64]float64{{0, 0}: 0, {1, 0}: 1, {0, 1}: 2}
+			for x, f := range tt.values {
+				values[x] = f
+			}
+			var evaluated [][2]float64
+			objective := func(x []float64) float64 {
+				key := [2]float64{x[0], x[1]}
+				evaluated = append(evaluated, key)
+				f, ok := values[key]
+				if !ok {
+					t.Fatalf("unexpected evaluation at %v", x)
+				}
+				return f
+			}
+			options := NewOptions()
+			options.InitialSimplex = GivenSimplex(Simplex{Points: []Point{{X: []float64{0, 0}}, {X: []float64{1, 0}}, {X: []float64{0, 1}}}})
+			o, err := newOptimizer(context.Background(), objectiveE(objective), []float64{0, 0}, options)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := o.evaluateSimplex(); err != nil {
+				t.Fatal(err)
+			}
+			evaluated = nil
+
+			got, err := o.step()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("expected operation %s got %s", tt.want, got)
+			}
+			if !slices.Equal(evaluated, tt.evaluated) {
+				t.Errorf("expected the points %v to be evaluated got %v", tt.evaluated, evaluated)
+			}
+			last := o.simplex.Points[2]
+			if key := [2]float64{last.X[0], last.X[1]}; key != tt.kept || last.F != values[tt.kept] {
+				t.Errorf("expected the worst vertex to be %v got %v", tt.kept, last)
+			}
+		})
+	}
+}
+
+func TestRunE(t *testing.T) {
+	errSimulation := errors.New("simulation failed")
+	// The objective fails to the left of x = 0.5 while the unconstrained
+	// minimum is at x = 0.
+	objective := func(x []float64) (float64, error) {
+		if x[0] < 0.5 {
+			return 0, errSimulation
+		}
+		return x[0]*x[0] + (x[1]-1)*(x[1]-1), nil
+	}
+
+	t.Run("abort", func(t *testing.T) {
+		result, err := RunE(context.Background(), objective, []float64{3, 3}, NewOptions())
+		if !errors.Is(err, errSimulation) {
+			t.Fatalf("expected error to wrap %v got %v", errSimulation, err)
+		}
+		var objectiveErr ErrorObjective
+		if !errors.As(err, &objectiveErr) {
+			t.Fatalf("expected an ErrorObjective got %T", err)
+		}
+		if objectiveErr.X[0] >= 0.5 {
+			t.Errorf("expected the error to carry the failing x got %v", objectiveErr.X)
+		}
+		if objectiveErr.Iteration != result.Iterations {
+			t.Errorf("expected the error to report iteration %d got %d", result.Iterations, objectiveErr.Iteration)
+		}
+		if result.Termination != TerminationObjectiveError {
+			t.Errorf("expected termination %q got %q", TerminationObjectiveError, result.Termination)
+		}
+		if math.IsInf(result.F, 0) || result.X[0] < 0.5 {
+			t.Errorf("expected the best point found so far got %v", result.Point)
+		}
+	})
+
+	t.Run("infeasible", func(t *testing.T) {
+		options := NewOptions()
+		options.ObjectiveErrors = ObjectiveErrorInfeasible
+
+		result, err := RunE(context.Background(), objective, []float64{3, 3}, options)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		expectPoint(t, Point{F: 0.25, X: []float64{0.5, 1}}, result.Point, 2)
+	})
+
+	t.Run("initial simplex", func(t *testing.T) {
+		_, err := RunE(context.Background(), objective, []float64{0, 0}, NewOptions())
+		var objectiveErr ErrorObjective
+		if !errors.As(err, &objectiveErr) {
+			t.Fatalf("expected an ErrorObjective got %v", err)
+		}
+		if objectiveErr.Iteration != 0 {
+			t.Errorf("expected iteration 0 got %d", objectiveErr.Iteration)
+		}
+	})
+}
+
+func TestOptions_NonFinite(t *testing.T) {
+	// The objective is NaN to the right of x = 1 so the second vertex of
+	// the initial simplex and some of the reflections are NaN.
+	objective := func(x []float64) float64 {
+		if x[0] >= 1 {
+			return math.NaN()
+		}
+		return x[0]*x[0] + x[1]*x[1]
+	}
+
+	for _, policy := range []NonFinitePolicy{NonFiniteAsInf, NonFiniteReject} {
+		t.Run(fmt.Sprintf("policy %d", policy), func(t *testing.T) {
+			options := NewOptions()
+			options.NonFinite = policy
+
+			result, err := RunWithResult(objective, []float64{0.5, 0.5}, options)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if result.Termination != TerminationConverged {
+				t.Errorf("expected termination %q got %q", TerminationConverged, result.Termination)
+			}
+			for _, p := range result.Simplex.Points {
+				if math.IsNaN(p.F) {
+					t.Errorf("expected no NaN in the final simplex got %v", p)
+				}
+			}
+			expectPoint(t, Point{F: 0, X: []float64{0, 0}}, result.Point, 2)
+		})
+	}
+
+	t.Run("reject moves NaN vertices", func(t *testing.T) {
+		options := NewOptions()
+		options.NonFinite = NonFiniteReject
+		o, err := newOptimizer(context.Background(), objectiveE(objective), []float64{0.5, 0.5}, options)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := o.evaluateSimplex(); err != nil {
+			t.Fatal(err)
+		}
+		for _, p := range o.simplex.Points {
+			if math.IsInf(p.F, 0) || math.IsNaN(p.F) {
+				t.Errorf("expected every vertex to have a finite value got %v", p)
+			}
+		}
+	})
+
+	t.Run("fail", func(t *testing.T) {
+		options := NewOptions()
+		options.NonFinite = NonFiniteFail
+
+		result, err := RunWithResult(objective, []float64{0.5, 0.5}, options)
+		var nonFiniteErr ErrorNonFiniteObjective
+		if !errors.As(err, &nonFiniteErr) {
+			t.Fatalf("expected an ErrorNonFiniteObjective got %v", err)
+		}
+		if !math.IsNaN(nonFiniteErr.F) {
+			t.Errorf("expected the error to carry NaN got %v", nonFiniteErr.F)
+		}
+		if nonFiniteErr.X[0] != 1.5 || nonFiniteErr.X[1] != 0.5 {
+			t.Errorf("expected the error to carry x = [1.5 0.5] got %v", nonFiniteErr.X)
+		}
+		if result.Termination != TerminationNonFiniteObjective {
+			t.Errorf("expected termination %q got %q", TerminationNonFiniteObjective, result.Termination)
+		}
+		if result.F != 0.5 {
+			t.Errorf("expected the best evaluated point got %v", result.Point)
+		}
+	})
+
+	t.Run("fail on infinity", func(t *testing.T) {
+		options := NewOptions()
+		options.NonFinite = NonFiniteFail
+
+		_, err := Run(func(x []float64) float64 { return 1 / x[0] }, []float64{0}, options)
+		if !errors.As(err, new(ErrorNonFiniteObjective)) {
+			t.Fatalf("expected an ErrorNonFiniteObjective got %v", err)
+		}
+	})
+}
+
+func TestOptions_XTolerance(t *testing.T) {
+	t.Run("plateau", func(t *testing.T) {
+		plateau := func(x []float64) float64 {
+			return math.Min(1, math.Pow(x[0]-5, 2)+math.Pow(x[1]-5, 2))
+		}
+
+		options := NewOptions()
+		result, err := RunWithResult(plateau, []float64{0, 0}, options)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if result.Iterations != 0 {
+			t.Errorf("expected the F criterion to be satisfied immediately got %d iterations", result.Iterations)
+		}
+
+		options.XTolerance = 1e-3
+		result, err = RunWithResult(plateau, []float64{0, 0}, options)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if result.Termination != TerminationConverged {
+			t.Errorf("expected termination %q got %q", TerminationConverged, result.Termination)
+		}
+		if result.Size >= options.XTolerance {
+			t.Errorf("expected simplex size below %g got %g", options.XTolerance, result.Size)
+		}
+	})
+
+	t.Run("steep", func(t *testing.T) {
+		steep := func(x []float64) float64 {
+			return 1e12 * (math.Pow(x[0]-1, 2) + math.Pow(x[1]-2, 2))
+		}
+
+		options := NewOptions()
+		options.XTolerance = 1e-3
+		both, err := RunWithResult(steep, []float64{0, 0}, options)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if both.Spread >= options.Tolerance || both.Size >= options.XTolerance {
+			t.Errorf("expected both criteria to be satisfied got spread %g and size %g", both.Spread, both.Size)
+		}
+
+		options.Convergence = ConvergenceX
+		x, err := RunWithResult(steep, []float64{0, 0}, options)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if x.Iterations >= both.Iterations {
+			t.Errorf("expected the x criterion alone to converge in fewer than %d iterations got %d", both.Iterations, x.Iterations)
+		}
+		if x.Size >= options.XTolerance {
+			t.Errorf("expected simplex size below %g got %g", options.XTolerance, x.Size)
+		}
+		expectPoint(t, Point{X: []float64{1, 2}, F: x.F}, x.Point, 3)
+
+		options.Convergence = ConvergenceF
+		f, err := RunWithResult(steep, []float64{0, 0}, options)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if f.Spread >= options.Tolerance {
+			t.Errorf("expected spread below %g got %g", options.Tolerance, f.Spread)
+		}
+	})
+}
+
+func TestOptions_RelativeTolerance(t *testing.T) {
+	scaled := func(scale float64) Objective {
+		return func(x []float64) float64 {
+			return scale * (1 + math.Pow(x[0]-1.1, 2) + 3*math.Pow(x[1]-2.3, 2))
+		}
+	}
+
+	t.Run("small values", func(t *testing.T) {
+		options := NewOptions()
+		result, err := RunWithResult(scaled(1e-12), []float64{0, 0}, options)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if result.Iterations != 0 {
+			t.Errorf("expected the absolute tolerance to be satisfied immediately got %d iterations", result.Iterations)
+		}
+
+		options.Tolerance = 0
+		options.RelativeTolerance = 1e-10
+		result, err = RunWithResult(scaled(1e-12), []float64{0, 0}, options)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if result.Termination != TerminationConverged {
+			t.Errorf("expected termination %q got %q", TerminationConverged, result.Termination)
+		}
+		expectPoint(t, Point{X: []float64{1.1, 2.3}, F: 1e-12}, result.Point, 3)
+	})
+
+	t.Run("large values", func(t *testing.T) {
+		options := NewOptions()
+		options.RelativeTolerance = 1e-10
+		result, err := RunWithResult(scaled(1e9), []float64{0, 0}, options)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if result.Termination != TerminationConverged {
+			t.Errorf("expected termination %q got %q", TerminationConverged, result.Termination)
+		}
+		if result.Spread > options.RelativeTolerance*result.F {
+			t.Errorf("expected spread %g to be below %g", result.Spread, options.RelativeTolerance*result.F)
+		}
+		expectPoint(t, Point{X: []float64{1.1, 2.3}, F: result.F}, result.Point, 3)
+	})
+}
+
+func TestOptions_MaxEvaluations(t *testing.T) {
+	rosenbrock := func(x []float64) float64 {
+		return math.Pow(1-x[0], 2) + 100*math.Pow(x[1]-x[0]*x[0], 2)
+	}
+
+	for _, maxEvaluations := range []int{1, 2, 3, 4, 7, 50, 80} {
+		t.Run(fmt.Sprintf("budget %d", maxEvaluations), func(t *testing.T) {
+			evaluations := 0
+			best := math.Inf(1)
+			objective := func(x []float64) float64 {
+				evaluations++
+				y := rosenbrock(x)
+				best = min(best, y)
+				return y
+			}
+
+			options := NewOptions()
+			options.MaxEvaluations = maxEvaluations
+
+			result, err := RunWithResult(objective, []float64{-1, -1}, options)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if evaluations != maxEvaluations || result.Evaluations != maxEvaluations {
+				t.Errorf("expected exactly %d evaluations got %d (result reports %d)", maxEvaluations, evaluations, result.Evaluations)
+			}
+			if result.Termination != TerminationMaxEvaluations {
+				t.Errorf("expected termination %q got %q", TerminationMaxEvaluations, result.Termination)
+			}
+			if result.F != best {
+				t.Errorf("expected the best evaluated point F = %g got %g", best, result.F)
+			}
+		})
+	}
+
+	t.Run("during a shrink", func(t *testing.T) {
+		// Every contraction of this objective fails so each iteration ends
+		// with a shrink that costs n evaluations.
+		evaluations := 0
+		objective := func(x []float64) float64 {
+			evaluations++
+			return math.Min(1, math.Abs(x[0])*1e9) + math.Min(1, math.Abs(x[1])*1e9) + math.Min(1, math.Abs(x[2])*1e9)
+		}
+		options := NewOptions()
+		options.MaxEvaluations = 4 + 2 + 1
+
+		result, err := RunWithResult(objective, []float64{0, 0, 0}, options)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if evaluations != options.MaxEvaluations {
+			t.Errorf("expected exactly %d evaluations got %d", options.MaxEvaluations, evaluations)
+		}
+		if result.Iterations != 0 {
+			t.Errorf("expected the budget to run out during the first iteration got %d iterations", result.Iterations)
+		}
+		if result.F != 0 {
+			t.Errorf("expected the best point to be x0 got %v", result.Point)
+		}
+	})
+}
+
+func TestOptions_MaxDuration(t *testing.T) {
+	rosenbrock := func(x []float64) float64 {
+		return math.Pow(1-x[0], 2) + 100*math.Pow(x[1]-x[0]*x[0], 2)
+	}
+
+	// Each objective function call takes one second on the fake clock.
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	evaluations := 0
+	objective := func(x []float64) float64 {
+		evaluations++
+		now = now.Add(time.Second)
+		return rosenbrock(x)
+	}
+
+	options := NewOptions()
+	options.MaxDuration = 10 * time.Second
+	options.Now = func() time.Time { return now }
+
+	result, err := RunWithResult(objective, []float64{-1, -1}, options)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Termination != TerminationMaxDuration {
+		t.Errorf("expected termination %q got %q", TerminationMaxDuration, result.Termination)
+	}
+	if evaluations != 10 {
+		t.Errorf("expected 10 evaluations got %d", evaluations)
+	}
+	if math.IsInf(result.F, 0) || len(result.X) != 2 {
+		t.Errorf("expected the best point found so far got %v", result.Point)
+	}
+
+	options.MaxDuration = time.Hour
+	options.Now = nil
+	result, err = RunWithResult(rosenbrock, []float64{-1, -1}, options)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Termination != TerminationConverged {
+		t.Errorf("expected termination %q got %q", TerminationConverged, result.Termination)
+	}
+}
+
 func TestSimplexCollapse(t *testing.T) {
 	src := rand.New(rand.NewSource(101))
 	flatRegionFunctionWithNoise := func(x []float64) float64 {
@@ -111,40 +806,518 @@ func TestSimplexCollapse(t *testing.T) {
 		CollapseThreshold: 1e-5,
 	}
 
-	_, err := Run(flatRegionFunctionWithNoise, initialGuess, options)
+	result, err := RunWithResult(flatRegionFunctionWithNoise, initialGuess, options)
 	if err == nil {
 		t.Fatalf("expected failure")
 	}
 	if !strings.Contains(err.Error(), ErrorSimplexCollapse{}.Error()) {
 		t.Errorf("expected error %q", ErrorSimplexCollapse{}.Error())
 	}
+	if result.Termination != TerminationSimplexCollapse {
+		t.Errorf("expected termination %q got %q", TerminationSimplexCollapse, result.Termination)
+	}
+
+	var collapse ErrorSimplexCollapse
+	if !errors.As(err, &collapse) {
+		t.Fatalf("expected an ErrorSimplexCollapse got %T", err)
+	}
+	if collapse.Point.F != result.F || collapse.Iteration != result.Iterations {
+		t.Errorf("expected the best point %v after %d iterations got %v after %d", result.Point, result.Iterations, collapse.Point, collapse.Iteration)
+	}
+	if collapse.AverageEdgeLength >= options.CollapseThreshold {
+		t.Errorf("expected an average edge length below %g got %g", options.CollapseThreshold, collapse.AverageEdgeLength)
+	}
+	if len(collapse.Simplex.Points) != len(initialGuess)+1 {
+		t.Errorf("expected the collapsed simplex to have %d points got %d", len(initialGuess)+1, len(collapse.Simplex.Points))
+	}
+
+	point, err := Run(flatRegionFunctionWithNoise, initialGuess, options)
+	if !errors.As(err, new(ErrorSimplexCollapse)) {
+		t.Fatalf("expected an ErrorSimplexCollapse got %v", err)
+	}
+	if len(point.X) != len(initialGuess) || math.Abs(point.X[0]-5) > 0.1 || math.Abs(point.X[1]-5) > 0.1 {
+		t.Errorf("expected Run to return the best point near [5 5] got %v", point)
+	}
+}
+
+func TestOptions_Restart(t *testing.T) {
+	rosenbrock := func(x []float64) float64 {
+		return math.Pow(1-x[0], 2) + 100*math.Pow(x[1]-x[0]*x[0], 2)
+	}
+
+	t.Run("on collapse", func(t *testing.T) {
+		options := NewOptions()
+		options.Tolerance = 1e-12
+		options.CollapseThreshold = 0.03
+
+		stuck, err := RunWithResult(rosenbrock, []float64{-1.2, 1}, options)
+		if !errors.As(err, new(ErrorSimplexCollapse)) {
+			t.Fatalf("expected the simplex to collapse without restarts got %v", err)
+		}
+
+		var calls int
+		counted := func(x []float64) float64 {
+			calls++
+			return rosenbrock(x)
+		}
+		options.Restart = RestartOnCollapse
+		options.MaxRestarts = 3
+		result, err := RunWithResult(counted, []float64{-1.2, 1}, options)
+		if !errors.As(err, new(ErrorSimplexCollapse)) {
+			t.Fatalf("expected the simplex to collapse once the restarts are used up got %v", err)
+		}
+		if result.Restarts != 3 {
+			t.Errorf("expected 3 restarts got %d", result.Restarts)
+		}
+		if result.F >= stuck.F {
+			t.Errorf("expected restarts to improve on %g got %g", stuck.F, result.F)
+		}
+		if result.Evaluations != calls {
+			t.Errorf("expected the restarts to count %d evaluations got %d", calls, result.Evaluations)
+		}
+	})
+
+	t.Run("on stagnation", func(t *testing.T) {
+		options := NewOptions()
+		options.Restart = RestartOnStagnation
+		options.StagnationIterations = 1
+		options.MaxRestarts = 3
+
+		result, err := RunWithResult(rosenbrock, []float64{-1.2, 1}, options)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if result.Restarts == 0 || result.Restarts > 3 {
+			t.Errorf("expected 1 to 3 restarts got %d", result.Restarts)
+		}
+		expectPoint(t, Point{X: []float64{1, 1}, F: 0}, result.Point, 2)
+	})
+
+	t.Run("a converging simplex is not stagnating", func(t *testing.T) {
+		// The best point is already the minimum so the best objective
+		// function value never decreases while the simplex shrinks onto it.
+		quadratic := func(x []float64) float64 {
+			return x[0]*x[0] + 10*x[1]*x[1]
+		}
+		exp, err := RunWithResult(quadratic, []float64{0, 0}, NewOptions())
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		options := NewOptions()
+		options.Restart = RestartOnStagnation
+		options.StagnationIterations = 10
+		result, err := RunWithResult(quadratic, []float64{0, 0}, options)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if result.Termination != TerminationConverged {
+			t.Errorf("expected %s got %s", TerminationConverged, result.Termination)
+		}
+		if result.Restarts != 0 || result.Iterations != exp.Iterations {
+			t.Errorf("expected to converge in %d iterations without restarts got %d iterations and %d restarts", exp.Iterations, result.Iterations, result.Restarts)
+		}
+	})
+
+	t.Run("counts against max iterations", func(t *testing.T) {
+		options := NewOptions()
+		options.Tolerance = 1e-300
+		options.CollapseThreshold = 1e-2
+		options.Restart = RestartOnCollapse
+		options.MaxIterations = 500
+
+		result, err := RunWithResult(rosenbrock, []float64{-1.2, 1}, options)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if result.Termination != TerminationMaxIterations || result.Iterations != 500 {
+			t.Errorf("expected to stop after 500 iterations got %d (%s)", result.Iterations, result.Termination)
+		}
+		if result.Restarts == 0 {
+			t.Errorf("expected at least one restart")
+		}
+	})
+}
+
+func TestOptions_BoundaryHandling(t *testing.T) {
+	constraints := []Constraint{{Min: -1, Max: 2}, {Min: 0, Max: 3}}
+
+	t.Run("moves points inside the bounds", func(t *testing.T) {
+		var (
+			halfBounded = []Constraint{{Min: -1, Max: math.Inf(1)}, {Min: math.Inf(-1), Max: 3}}
+			unbounded   = []Constraint{{Min: math.Inf(-1), Max: math.Inf(1)}, {Min: math.Inf(-1), Max: math.Inf(1)}}
+		)
+		for _, tt := range []struct {
+			name             string
+			boundaryHandling BoundaryHandling
+			constraints      []Constraint
+			x, want          []float64
+		}{
+			{name: "clamp", boundaryHandling: BoundaryClamp, x: []float64{-1.5, 3.5}, want: []float64{-1, 3}},
+			{name: "reflect", boundaryHandling: BoundaryReflect, x: []float64{-1.5, 3.5}, want: []float64{-0.5, 2.5}},
+			{name: "reflect far outside", boundaryHandling: BoundaryReflect, x: []float64{5.5, -7}, want: []float64{-0.5, 1}},
+			{name: "wrap", boundaryHandling: BoundaryWrap, x: []float64{-1.5, 3.5}, want: []float64{1.5, 0.5}},
+			{name: "wrap far outside", boundaryHandling: BoundaryWrap, x: []float64{5.5, -7}, want: []float64{-0.5, 2}},
+			{name: "inside", boundaryHandling: BoundaryReflect, x: []float64{0.5, 1}, want: []float64{0.5, 1}},
+			{name: "clamp half bounded", boundaryHandling: BoundaryClamp, constraints: halfBounded, x: []float64{-2, 5}, want: []float64{-1, 3}},
+			{name: "reflect half bounded", boundaryHandling: BoundaryReflect, constraints: halfBounded, x: []float64{-2, 5}, want: []float64{0, 1}},
+			{name: "wrap half bounded", boundaryHandling: BoundaryWrap, constraints: halfBounded, x: []float64{-2, 5}, want: []float64{0, 1}},
+			{name: "resample half bounded", boundaryHandling: BoundaryResample, constraints: halfBounded, x: []float64{-2, 5}, want: []float64{0, 1}},
+			{name: "unbounded", boundaryHandling: BoundaryWrap, constraints: unbounded, x: []float64{-1e300, 1e300}, want: []float64{-1e300, 1e300}},
+		} {
+			t.Run(tt.name, func(t *testing.T) {
+				options := NewOptions()
+				options.Constraints = constraints
+				if tt.constraints != nil {
+					options.Constraints = tt.constraints
+				}
+				options.BoundaryHandling = tt.boundaryHandling
+				o, err := newOptimizer(context.Background(), nil, []float64{0, 0}, options)
+				if err != nil {
+					t.Fatal(err)
+				}
+				x := append([]float64(nil), tt.x...)
+				o.bound(x)
+				for i := range x {
+					if math.Abs(x[i]-tt.want[i]) > 1e-12 {
+						t.Errorf("expected %v got %v", tt.want, x)
+						break
+					}
+				}
+			})
+		}
+	})
+
+	// The minimum is inside the box but close to a corner the simplex
+	// overshoots, so every mode has to move candidates back inside.
+	for name, boundaryHandling := range map[string]BoundaryHandling{
+		"clamp":    BoundaryClamp,
+		"reflect":  BoundaryReflect,
+		"wrap":     BoundaryWrap,
+		"resample": BoundaryResample,
+	} {
+		t.Run(name+" evaluates inside the bounds", func(t *testing.T) {
+			objective := func(x []float64) float64 {
+				requireXToBeWithinConstraints(t, x, constraints)
+				return math.Pow(x[0]-1.9, 2) + math.Pow(x[1]-2.9, 2)
+			}
+			options := NewOptions()
+			options.Constraints = constraints
+			options.BoundaryHandling = boundaryHandling
+			options.Source = rand.NewSource(3)
+
+			result, err := RunWithResult(objective, []float64{0.5, 0.5}, options)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if boundaryHandling == BoundaryClamp {
+				// Clamped vertices pile up in the corner so the simplex
+				// degenerates before reaching the minimum.
+				return
+			}
+			expectPoint(t, Point{X: []float64{1.9, 2.9}, F: 0}, result.Point, 2)
+		})
+	}
+}
+
+func TestConstraints_infinite(t *testing.T) {
+	// x0 only has to be positive, x1 has an upper bound below the
+	// unconstrained minimum, and x2 is not bounded at all.
+	constraints := []Constraint{
+		{Min: 0, Max: math.Inf(1)},
+		{Min: math.Inf(-1), Max: -3},
+		{Min: math.Inf(-1), Max: math.Inf(1)},
+	}
+	objective := feasibleObjective(t, constraints, func(x []float64) float64 {
+		return math.Pow(math.Log(x[0])-1, 2) + math.Abs(x[1]+2) + math.Pow(x[2]-5, 2)
+	})
+
+	for _, variant := range feasibilityVariants {
+		for name, boundaryHandling := range map[string]BoundaryHandling{
+			"clamp":    BoundaryClamp,
+			"reflect":  BoundaryReflect,
+			"wrap":     BoundaryWrap,
+			"resample": BoundaryResample,
+		} {
+			t.Run(variant.name+"/"+name, func(t *testing.T) {
+				options := NewOptions()
+				options.Tolerance = 1e-10
+				options.Constraints = constraints
+				options.BoundaryHandling = boundaryHandling
+
+				point, err := variant.run(objective, []float64{0.5, -10, 0}, options)
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				requireXToBeWithinConstraints(t, point.X, constraints)
+				expectPoint(t, Point{X: []float64{math.E, -3, 5}, F: 1}, point, 2)
+			})
+		}
+	}
+
+	t.Run("coordinates stay finite", func(t *testing.T) {
+		// The objective function decreases without bound along x0 and the
+		// initial simplex is so wide along x0 that reflecting through the
+		// centroid overflows.
+		constraints := []Constraint{{Min: 0, Max: math.Inf(1)}, {Min: -1, Max: 1}}
+		var calls int
+		objective := func(x []float64) float64 {
+			calls++
+			for i, xi := range x {
+				if math.IsNaN(xi) || math.IsInf(xi, 0) {
+					t.Fatalf("objective function called with x[%d] = %g", i, xi)
+				}
+			}
+			return -x[0] + x[1]*x[1]
+		}
+		options := NewOptions()
+		options.Constraints = constraints
+		options.MaxIterations = 5000
+		options.InitialSimplex = StepSimplex(1e308, 1)
+
+		for _, variant := range feasibilityVariants {
+			t.Run(variant.name, func(t *testing.T) {
+				point, err := variant.run(feasibleObjective(t, constraints, objective), []float64{1, 0.5}, options)
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if math.IsInf(point.X[0], 0) || math.IsInf(point.F, 0) {
+					t.Errorf("expected a finite best point got %v", point)
+				}
+			})
+		}
+
+		calls = 0
+		result, err := RunWithResult(objective, []float64{1, 0.5}, options)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if result.Evaluations != calls {
+			t.Errorf("expected only the %d objective function calls to be counted got %d", calls, result.Evaluations)
+		}
+	})
+}
+
+func TestConstraints_fixed(t *testing.T) {
+	free := func(x []float64) float64 {
+		return math.Pow(1-x[0], 2) + 100*math.Pow(x[1]-x[0]*x[0], 2)
+	}
+	// Dimensions 1 and 3 are fixed to 7 and -2.
+	constraints := []Constraint{
+		{Min: math.Inf(-1), Max: math.Inf(1)},
+		{Min: 7, Max: 7},
+		{Min: math.Inf(-1), Max: math.Inf(1)},
+		{Min: -2, Max: -2},
+	}
+	full := func(x []float64) float64 {
+		if len(x) != 4 || x[1] != 7 || x[3] != -2 {
+			t.Errorf("expected the objective function to be called with the fixed values got %v", x)
+		}
+		return free([]float64{x[0], x[2]})
+	}
+
+	t.Run("optimizes the free dimensions", func(t *testing.T) {
+		options := NewOptions()
+		exp, err := RunWithResult(free, []float64{-1.2, 1}, options)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		options.Constraints = constraints
+		var observed int
+		options.Observer = func(info IterationInfo) bool {
+			observed++
+			if len(info.Best.X) != 4 {
+				t.Errorf("expected the observer to receive every dimension got %v", info.Best.X)
+			}
+			return false
+		}
+		got, err := RunWithResult(full, []float64{-1.2, 7, 1, -2}, options)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		// The smaller simplex takes exactly the same steps as optimizing
+		// the free dimensions on their own.
+		if exp.F != got.F || exp.Iterations != got.Iterations || exp.Evaluations != got.Evaluations {
+			t.Errorf("expected F=%g iterations=%d evaluations=%d got F=%g iterations=%d evaluations=%d",
+				exp.F, exp.Iterations, exp.Evaluations, got.F, got.Iterations, got.Evaluations)
+		}
+		expectPoint(t, Point{X: []float64{exp.X[0], 7, exp.X[1], -2}, F: exp.F}, got.Point, 12)
+		if len(got.Simplex.Points) != 3 {
+			t.Errorf("expected a simplex with 3 points got %d", len(got.Simplex.Points))
+		}
+		for _, p := range got.Simplex.Points {
+			requireXToBeWithinConstraints(t, p.X, constraints)
+		}
+		if observed != got.Iterations {
+			t.Errorf("expected %d observations got %d", got.Iterations, observed)
+		}
+	})
+
+	t.Run("steps per dimension", func(t *testing.T) {
+		options := NewOptions()
+		options.Constraints = constraints
+		options.InitialSimplex = StepSimplex(0.5, 0, 0.5, 0)
+		if _, err := Run(full, []float64{-1.2, 7, 1, -2}, options); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("every dimension fixed", func(t *testing.T) {
+		options := NewOptions()
+		options.Constraints = []Constraint{{Min: 1, Max: 1}, {Min: 1, Max: 1}}
+		result, err := RunWithResult(free, []float64{1, 1}, options)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if result.Evaluations != 1 || result.Termination != TerminationConverged {
+			t.Errorf("expected a single evaluation got %d (%s)", result.Evaluations, result.Termination)
+		}
+		if result.EdgeLength != 0 || result.Size != 0 || result.Spread != 0 {
+			t.Errorf("expected a simplex of a single point to have no extent got edge length %g, size %g, and spread %g", result.EdgeLength, result.Size, result.Spread)
+		}
+		expectPoint(t, Point{X: []float64{1, 1}, F: 0}, result.Point, 12)
+	})
+
+	for _, variant := range feasibilityVariants {
+		t.Run(variant.name, func(t *testing.T) {
+			options := NewOptions()
+			options.Constraints = []Constraint{{Min: -2, Max: 2}, {Min: 7, Max: 7}, {Min: -2, Max: 2}, {Min: -2, Max: -2}}
+			point, err := variant.run(feasibleObjective(t, options.Constraints, full), []float64{-1.2, 7, 1, -2}, options)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(point.X) != 4 || point.X[1] != 7 || point.X[3] != -2 {
+				t.Errorf("expected the fixed values in %v", point.X)
+			}
+		})
+	}
 }
 
 func FuzzRun_quadratic(f *testing.F) {
 	f.Add(0.0, 0.0, -1.0, -1.0, 2.0, 3.0, 1.0, 2.0, -1.0, -2.0)
 	f.Add(0.0, 3.0, -1.0, -1.0, 2.0, 3.0, 1.0, 2.0, -1.0, -2.0)
+	f.Add(0.5, 0.5, 0.0, 1.0, 0.0, 1.0, -3.0, 2.0, 2.0, 2.0)
+	f.Add(1.0, -1.0, 0.0, 2.0, -2.0, 0.0, 0.5, -4.0, 0.5, 3.0)
+	f.Add(-2.0, 7.0, -10.0, -1.0, 5.0, 8.0, 20.0, -0.1, 4.0, 1.0)
 	f.Fuzz(func(t *testing.T, xInitial1, xInitial2, min1, max1, min2, max2, m1, m2, exponent1, exponent2 float64) {
+		// Define the starting point and Constraints
+		x := []float64{xInitial1, xInitial2}
+		constraints := []Constraint{{Min: min1, Max: max1}, {Min: min2, Max: max2}}
+
 		// Define the objective function to optimize
-		objective := func(x []float64) float64 {
+		objective := feasibleObjective(t, constraints, func(x []float64) float64 {
 			return math.Pow(x[0]+m1, exponent1) + math.Pow(x[1]+m2, exponent2)
+		})
+
+		for _, variant := range feasibilityVariants {
+			for _, boundaryHandling := range []BoundaryHandling{BoundaryClamp, BoundaryReflect, BoundaryWrap, BoundaryResample} {
+				// Set the options for the optimizer
+				options := NewOptions()
+				options.Constraints = constraints
+				options.BoundaryHandling = boundaryHandling
+
+				// Run the optimizer
+				result, err := variant.run(objective, x, options)
+				if err != nil {
+					continue
+				}
+				// Check that the result is within the feasible region
+				requireXToBeWithinConstraints(t, result.X, constraints)
+			}
 		}
+	})
+}
 
-		// Define the starting point and Constraints
-		x := []float64{xInitial1, xInitial2}
-		constraints := []Constraint{{Min: min1, Max: max1}, {Min: min2, Max: max2}}
+// feasibilityVariants run an optimization through each code path that
+// evaluates the objective function.
+var feasibilityVariants = []struct {
+	name string
+	run  func(f Objective, x0 []float64, options Options) (Point, error)
+}{
+	{name: "sequential", run: Run},
+	{name: "speculative", run: func(f Objective, x0 []float64, options Options) (Point, error) {
+		options.Speculative = true
+		return Run(f, x0, options)
+	}},
+	{name: "parallelism", run: func(f Objective, x0 []float64, options Options) (Point, error) {
+		options.Parallelism = 4
+		return Run(f, x0, options)
+	}},
+	{name: "reject non-finite", run: func(f Objective, x0 []float64, options Options) (Point, error) {
+		options.NonFinite = NonFiniteReject
+		return Run(f, x0, options)
+	}},
+	{name: "restart", run: func(f Objective, x0 []float64, options Options) (Point, error) {
+		// Restarts are not limited so a collapse always restarts instead of
+		// returning ErrorSimplexCollapse.
+		options.Restart = RestartOnCollapse | RestartOnStagnation
+		options.StagnationIterations = 10
+		options.CollapseThreshold = 1e-6
+		return Run(f, x0, options)
+	}},
+	{name: "sine transform", run: func(f Objective, x0 []float64, options Options) (Point, error) {
+		options.Transform = TransformSine
+		return Run(f, x0, options)
+	}},
+	{name: "logistic transform", run: func(f Objective, x0 []float64, options Options) (Point, error) {
+		options.Transform = TransformLogistic
+		return Run(f, x0, options)
+	}},
+	{name: "parallel vertices", run: func(f Objective, x0 []float64, options Options) (Point, error) {
+		if len(x0) < 2 {
+			return RunParallel(f, x0, 1, options)
+		}
+		return RunParallel(f, x0, 2, options)
+	}},
+}
 
-		// Set the options for the optimizer
-		options := NewOptions()
-		options.Constraints = constraints
+// feasibleObjective wraps f so the test fails when the objective function is
+// called with an x outside the constraints.
+func feasibleObjective(t *testing.T, constraints []Constraint, f Objective) Objective {
+	t.Helper()
+	return func(x []float64) float64 {
+		for i, c := range constraints {
+			if !(x[i] >= c.Min && x[i] <= c.Max) {
+				t.Errorf("objective function called outside the constraints: x[%d] = %g is not in [%g, %g]", i, x[i], c.Min, c.Max)
+			}
+		}
+		return f(x)
+	}
+}
 
-		// Run the optimizer
-		result, err := Run(objective, x, options)
-		if err != nil {
-			return
+func TestConstraints_strict(t *testing.T) {
+	constraints := []Constraint{{Min: 0, Max: 1}, {Min: 1, Max: 4}, {Min: -3, Max: -2}}
+	// The logarithm is only defined inside the bounds and the unconstrained
+	// minimum is far outside of them.
+	objective := feasibleObjective(t, constraints, func(x []float64) float64 {
+		if x[0] < 0 || x[1] < 1 || x[2] > -2 {
+			panic(fmt.Sprintf("x = %v is outside the domain", x))
 		}
-		// Check that the result is within the feasible region
-		requireXToBeWithinConstraints(t, result.X, constraints)
+		return -math.Log(x[0]) - 3*math.Log(x[1]) + math.Log(-1-x[2]) + 10*x[0]*x[2]
 	})
+
+	for _, variant := range feasibilityVariants {
+		for name, boundaryHandling := range map[string]BoundaryHandling{
+			"clamp":    BoundaryClamp,
+			"reflect":  BoundaryReflect,
+			"wrap":     BoundaryWrap,
+			"resample": BoundaryResample,
+		} {
+			t.Run(variant.name+"/"+name, func(t *testing.T) {
+				options := NewOptions()
+				options.Constraints = constraints
+				options.BoundaryHandling = boundaryHandling
+
+				result, err := variant.run(objective, []float64{0.5, 2, -2.5}, options)
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				requireXToBeWithinConstraints(t, result.X, constraints)
+			})
+		}
+	}
 }
 
 func requireXToBeWithinConstraints(t *testing.T, x []float64, constraints []Constraint) {
@@ -183,6 +1356,129 @@ func BenchmarkRun(b *testing.B) {
 	}
 }
 
+func BenchmarkRunIteration_evaluations(b *testing.B) {
+	const n = 10
+	evaluations := 0
+	objective := func(x []float64) float64 {
+		evaluations++
+		sum := 0.0
+		for i, xi := range x {
+			sum += float64(i+1) * (xi - 1) * (xi - 1)
+		}
+		return sum
+	}
+	options := NewOptions()
+
+	iterations, total := 0, 0
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		o, err := newOptimizer(context.Background(), objectiveE(objective), make([]float64, n), options)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if err := o.evaluateSimplex(); err != nil {
+			b.Fatal(err)
+		}
+		evaluations = 0
+		b.StartTimer()
+
+		for iter := 0; iter < options.MaxIterations; iter++ {
+			if hasConverged(o.simplex, options) {
+				break
+			}
+			before := evaluations
+			operation, err := o.iterate()
+			if err != nil {
+				b.Fatal(err)
+			}
+			iterations++
+			// A reflection is always followed by either an expansion or a
+			// contraction. Only a shrink evaluates the remaining n vertices.
+			want := 2
+			if operation == OperationShrink {
+				want = 2 + n
+			}
+			if got := evaluations - before; got != want {
+				b.Fatalf("iteration %d: expected %d objective evaluations for %s got %d", iter, want, operation, got)
+			}
+		}
+		total += evaluations
+	}
+	if iterations > 0 {
+		b.ReportMetric(float64(total)/float64(iterations), "evals/iter")
+	}
+}
+
+func TestNewAdaptiveOptions(t *testing.T) {
+	for _, n := range []int{1, 2} {
+		if got := NewAdaptiveOptions(n); got.Alpha != DefaultAlpha || got.Beta != DefaultBeta || got.Gamma != DefaultGamma || got.Delta != DefaultDelta {
+			t.Errorf("n=%d: expected the default coefficients", n)
+		}
+	}
+	for _, n := range []int{3, 10, 30, 100} {
+		options := NewAdaptiveOptions(n)
+		if err := options.validate(); err != nil {
+			t.Errorf("n=%d: %v", n, err)
+		}
+	}
+	options := NewAdaptiveOptions(10)
+	if options.Gamma != 1.2 || options.Beta != 0.7 || options.Delta != 0.9 {
+		t.Errorf("unexpected coefficients for n=10: %+v", options)
+	}
+}
+
+func BenchmarkNewAdaptiveOptions(b *testing.B) {
+	quadratic := func(x []float64) float64 {
+		sum := 0.0
+		for i, xi := range x {
+			sum += float64(i+1) * (xi - 1) * (xi - 1)
+		}
+		return sum
+	}
+	rosenbrock := func(x []float64) float64 {
+		sum := 0.0
+		for i := 0; i < len(x)-1; i++ {
+			a, c := 1-x[i], x[i+1]-x[i]*x[i]
+			sum += a*a + 100*c*c
+		}
+		return sum
+	}
+	for _, problem := range []struct {
+		name      string
+		objective Objective
+	}{
+		{name: "quadratic", objective: quadratic},
+		{name: "rosenbrock", objective: rosenbrock},
+	} {
+		for _, n := range []int{10, 20, 30} {
+			for _, coefficients := range []struct {
+				name    string
+				options Options
+			}{
+				{name: "fixed", options: NewOptions()},
+				{name: "adaptive", options: NewAdaptiveOptions(n)},
+			} {
+				b.Run(fmt.Sprintf("%s/n=%d/%s", problem.name, n, coefficients.name), func(b *testing.B) {
+					options := coefficients.options
+					options.MaxIterations = 200 * n
+					options.Tolerance = 1e-10
+					var result Result
+					for i := 0; i < b.N; i++ {
+						var err error
+						result, err = RunWithResult(problem.objective, make([]float64, n), options)
+						if err != nil {
+							b.Fatal(err)
+						}
+					}
+					b.ReportMetric(float64(result.Evaluations), "evals")
+					b.ReportMetric(result.F, "f")
+				})
+			}
+		}
+	}
+}
+
 func expectPoint(t *testing.T, exp, got Point, decimalAccuracy int) {
 	t.Helper()
 	diff := math.Pow10(-decimalAccuracy)
@@ -335,7 +1631,7 @@ func TestOptions_Validate(t *testing.T) {
 			wantError: true,
 		},
 		{
-			name: "Constraint with equal upper and lower bounds",
+			name: "Constraint with equal upper and lower bounds fixes the dimension",
 			options: Options{
 				Alpha:         DefaultAlpha,
 				Beta:          DefaultBeta,
@@ -347,10 +1643,10 @@ func TestOptions_Validate(t *testing.T) {
 					{Min: 1, Max: 1},
 				},
 			},
-			wantError: true,
+			wantError: false,
 		},
 		{
-			name: "Constraint values must not be infinite",
+			name: "Constraint values may be infinite",
 			options: Options{
 				Alpha:         DefaultAlpha,
 				Beta:          DefaultBeta,
@@ -360,6 +1656,23 @@ func TestOptions_Validate(t *testing.T) {
 				MaxIterations: DefaultMaxIterations,
 				Constraints: []Constraint{
 					{Min: math.Inf(-1), Max: math.Inf(1)},
+					{Min: 0, Max: math.Inf(1)},
+					{Min: math.Inf(-1), Max: 0},
+				},
+			},
+			wantError: false,
+		},
+		{
+			name: "Constraint Min must not be +Inf",
+			options: Options{
+				Alpha:         DefaultAlpha,
+				Beta:          DefaultBeta,
+				Gamma:         DefaultGamma,
+				Delta:         DefaultDelta,
+				Tolerance:     DefaultTolerance,
+				MaxIterations: DefaultMaxIterations,
+				Constraints: []Constraint{
+					{Min: math.Inf(1), Max: math.Inf(1)},
 				},
 			},
 			wantError: true,
@@ -409,6 +1722,307 @@ func TestOptions_Validate(t *testing.T) {
 			},
 			wantError: true,
 		},
+		{
+			name: "Unknown ObjectiveErrors policy",
+			options: Options{
+				Alpha:           DefaultAlpha,
+				Beta:            DefaultBeta,
+				Gamma:           DefaultGamma,
+				Delta:           DefaultDelta,
+				Tolerance:       DefaultTolerance,
+				MaxIterations:   DefaultMaxIterations,
+				ObjectiveErrors: ObjectiveErrorInfeasible + 1,
+			},
+			wantError: true,
+		},
+		{
+			name: "Unknown NonFinite policy",
+			options: Options{
+				Alpha:         DefaultAlpha,
+				Beta:          DefaultBeta,
+				Gamma:         DefaultGamma,
+				Delta:         DefaultDelta,
+				Tolerance:     DefaultTolerance,
+				MaxIterations: DefaultMaxIterations,
+				NonFinite:     NonFiniteFail + 1,
+			},
+			wantError: true,
+		},
+		{
+			name: "Negative XTolerance",
+			options: Options{
+				Alpha:         DefaultAlpha,
+				Beta:          DefaultBeta,
+				Gamma:         DefaultGamma,
+				Delta:         DefaultDelta,
+				Tolerance:     DefaultTolerance,
+				XTolerance:    -1,
+				MaxIterations: DefaultMaxIterations,
+			},
+			wantError: true,
+		},
+		{
+			name: "ConvergenceX without XTolerance",
+			options: Options{
+				Alpha:         DefaultAlpha,
+				Beta:          DefaultBeta,
+				Gamma:         DefaultGamma,
+				Delta:         DefaultDelta,
+				Tolerance:     DefaultTolerance,
+				MaxIterations: DefaultMaxIterations,
+				Convergence:   ConvergenceX,
+			},
+			wantError: true,
+		},
+		{
+			name: "Zero Tolerance with RelativeTolerance",
+			options: Options{
+				Alpha:             DefaultAlpha,
+				Beta:              DefaultBeta,
+				Gamma:             DefaultGamma,
+				Delta:             DefaultDelta,
+				RelativeTolerance: 1e-9,
+				MaxIterations:     DefaultMaxIterations,
+			},
+			wantError: false,
+		},
+		{
+			name: "Negative RelativeTolerance",
+			options: Options{
+				Alpha:             DefaultAlpha,
+				Beta:              DefaultBeta,
+				Gamma:             DefaultGamma,
+				Delta:             DefaultDelta,
+				Tolerance:         DefaultTolerance,
+				RelativeTolerance: -1,
+				MaxIterations:     DefaultMaxIterations,
+			},
+			wantError: true,
+		},
+		{
+			name: "Negative MaxEvaluations",
+			options: Options{
+				Alpha:          DefaultAlpha,
+				Beta:           DefaultBeta,
+				Gamma:          DefaultGamma,
+				Delta:          DefaultDelta,
+				Tolerance:      DefaultTolerance,
+				MaxIterations:  DefaultMaxIterations,
+				MaxEvaluations: -1,
+			},
+			wantError: true,
+		},
+		{
+			name: "Negative MaxDuration",
+			options: Options{
+				Alpha:         DefaultAlpha,
+				Beta:          DefaultBeta,
+				Gamma:         DefaultGamma,
+				Delta:         DefaultDelta,
+				Tolerance:     DefaultTolerance,
+				MaxIterations: DefaultMaxIterations,
+				MaxDuration:   -time.Second,
+			},
+			wantError: true,
+		},
+		{
+			name: "Unknown Restart policy",
+			options: Options{
+				Alpha:         DefaultAlpha,
+				Beta:          DefaultBeta,
+				Gamma:         DefaultGamma,
+				Delta:         DefaultDelta,
+				Tolerance:     DefaultTolerance,
+				MaxIterations: DefaultMaxIterations,
+				Restart:       RestartOnStagnation << 1,
+			},
+			wantError: true,
+		},
+		{
+			name: "Negative MaxRestarts",
+			options: Options{
+				Alpha:         DefaultAlpha,
+				Beta:          DefaultBeta,
+				Gamma:         DefaultGamma,
+				Delta:         DefaultDelta,
+				Tolerance:     DefaultTolerance,
+				MaxIterations: DefaultMaxIterations,
+				Restart:       RestartOnCollapse,
+				MaxRestarts:   -1,
+			},
+			wantError: true,
+		},
+		{
+			name: "RestartOnStagnation without StagnationIterations",
+			options: Options{
+				Alpha:         DefaultAlpha,
+				Beta:          DefaultBeta,
+				Gamma:         DefaultGamma,
+				Delta:         DefaultDelta,
+				Tolerance:     DefaultTolerance,
+				MaxIterations: DefaultMaxIterations,
+				Restart:       RestartOnStagnation,
+			},
+			wantError: true,
+		},
+		{
+			name: "Unknown BoundaryHandling",
+			options: Options{
+				Alpha:            DefaultAlpha,
+				Beta:             DefaultBeta,
+				Gamma:            DefaultGamma,
+				Delta:            DefaultDelta,
+				Tolerance:        DefaultTolerance,
+				MaxIterations:    DefaultMaxIterations,
+				BoundaryHandling: BoundaryResample + 1,
+			},
+			wantError: true,
+		},
+		{
+			name: "Unknown Transform",
+			options: Options{
+				Alpha:         DefaultAlpha,
+				Beta:          DefaultBeta,
+				Gamma:         DefaultGamma,
+				Delta:         DefaultDelta,
+				Tolerance:     DefaultTolerance,
+				MaxIterations: DefaultMaxIterations,
+				Transform:     TransformLogistic + 1,
+			},
+			wantError: true,
+		},
+		{
+			name: "Unknown InequalityHandling",
+			options: Options{
+				Alpha:              DefaultAlpha,
+				Beta:               DefaultBeta,
+				Gamma:              DefaultGamma,
+				Delta:              DefaultDelta,
+				Tolerance:          DefaultTolerance,
+				MaxIterations:      DefaultMaxIterations,
+				InequalityHandling: InequalityBarrier + 1,
+			},
+			wantError: true,
+		},
+		{
+			name: "Nil InequalityConstraints function",
+			options: Options{
+				Alpha:                 DefaultAlpha,
+				Beta:                  DefaultBeta,
+				Gamma:                 DefaultGamma,
+				Delta:                 DefaultDelta,
+				Tolerance:             DefaultTolerance,
+				MaxIterations:         DefaultMaxIterations,
+				InequalityConstraints: []func([]float64) float64{nil},
+				PenaltyWeight:         DefaultPenaltyWeight,
+			},
+			wantError: true,
+		},
+		{
+			name: "InequalityConstraints without PenaltyWeight",
+			options: Options{
+				Alpha:                 DefaultAlpha,
+				Beta:                  DefaultBeta,
+				Gamma:                 DefaultGamma,
+				Delta:                 DefaultDelta,
+				Tolerance:             DefaultTolerance,
+				MaxIterations:         DefaultMaxIterations,
+				InequalityConstraints: []func([]float64) float64{func([]float64) float64 { return 0 }},
+			},
+			wantError: true,
+		},
+		{
+			name: "InequalityBarrier without PenaltyWeight",
+			options: Options{
+				Alpha:                 DefaultAlpha,
+				Beta:                  DefaultBeta,
+				Gamma:                 DefaultGamma,
+				Delta:                 DefaultDelta,
+				Tolerance:             DefaultTolerance,
+				MaxIterations:         DefaultMaxIterations,
+				InequalityConstraints: []func([]float64) float64{func([]float64) float64 { return 0 }},
+				InequalityHandling:    InequalityBarrier,
+			},
+			wantError: false,
+		},
+		{
+			name: "Negative ConstraintTolerance",
+			options: Options{
+				Alpha:               DefaultAlpha,
+				Beta:                DefaultBeta,
+				Gamma:               DefaultGamma,
+				Delta:               DefaultDelta,
+				Tolerance:           DefaultTolerance,
+				MaxIterations:       DefaultMaxIterations,
+				ConstraintTolerance: -1,
+			},
+			wantError: true,
+		},
+		{
+			name: "LinearEquality with more rows in A than B",
+			options: Options{
+				Alpha:          DefaultAlpha,
+				Beta:           DefaultBeta,
+				Gamma:          DefaultGamma,
+				Delta:          DefaultDelta,
+				Tolerance:      DefaultTolerance,
+				MaxIterations:  DefaultMaxIterations,
+				LinearEquality: LinearEquality{A: [][]float64{{1, 1}, {1, 0}}, B: []float64{1}},
+			},
+			wantError: true,
+		},
+		{
+			name: "LinearEquality with rows of different lengths",
+			options: Options{
+				Alpha:          DefaultAlpha,
+				Beta:           DefaultBeta,
+				Gamma:          DefaultGamma,
+				Delta:          DefaultDelta,
+				Tolerance:      DefaultTolerance,
+				MaxIterations:  DefaultMaxIterations,
+				LinearEquality: LinearEquality{A: [][]float64{{1, 1}, {1}}, B: []float64{1, 0}},
+			},
+			wantError: true,
+		},
+		{
+			name: "LinearEquality with NaN",
+			options: Options{
+				Alpha:          DefaultAlpha,
+				Beta:           DefaultBeta,
+				Gamma:          DefaultGamma,
+				Delta:          DefaultDelta,
+				Tolerance:      DefaultTolerance,
+				MaxIterations:  DefaultMaxIterations,
+				LinearEquality: LinearEquality{A: [][]float64{{1, math.NaN()}}, B: []float64{1}},
+			},
+			wantError: true,
+		},
+		{
+			name: "LinearEquality with infinite B",
+			options: Options{
+				Alpha:          DefaultAlpha,
+				Beta:           DefaultBeta,
+				Gamma:          DefaultGamma,
+				Delta:          DefaultDelta,
+				Tolerance:      DefaultTolerance,
+				MaxIterations:  DefaultMaxIterations,
+				LinearEquality: LinearEquality{A: [][]float64{{1, 1}}, B: []float64{math.Inf(1)}},
+			},
+			wantError: true,
+		},
+		{
+			name: "LinearEquality",
+			options: Options{
+				Alpha:          DefaultAlpha,
+				Beta:           DefaultBeta,
+				Gamma:          DefaultGamma,
+				Delta:          DefaultDelta,
+				Tolerance:      DefaultTolerance,
+				MaxIterations:  DefaultMaxIterations,
+				LinearEquality: LinearEquality{A: [][]float64{{1, 1}}, B: []float64{1}},
+			},
+			wantError: false,
+		},
 		// Add more test cases here...
 	}
 
@@ -471,6 +2085,30 @@ func TestOptions_validateX0(t *testing.T) {
 			x0:      []float64{2},
 			wantErr: false,
 		},
+		{
+			name: "x is infinite in an unbounded dimension",
+			cs: []Constraint{
+				{Min: math.Inf(-1), Max: math.Inf(1)},
+			},
+			x0:      []float64{math.Inf(1)},
+			wantErr: true,
+		},
+		{
+			name: "x is inside a half bounded constraint",
+			cs: []Constraint{
+				{Min: 0, Max: math.Inf(1)},
+			},
+			x0:      []float64{1e300},
+			wantErr: false,
+		},
+		{
+			name: "x is NaN",
+			cs: []Constraint{
+				{Min: -2, Max: 2},
+			},
+			x0:      []float64{math.NaN()},
+			wantErr: true,
+		},
 		{
 			name: "wrong number of constraints",
 			cs: []Constraint{
@@ -0,0 +1,122 @@
+package neldermead
+
+import (
+	"context"
+	"slices"
+)
+
+// userSpace maps a point from a search space the optimizer works in to the
+// space of the objective function. It is used for the mappings configured
+// by Options.Transform, for fixed dimensions, and for Options.LinearEquality.
+type userSpace func(z []float64) []float64
+
+// run optimizes f in the internal space starting from z0. The objective
+// function and Observer receive points in the space of the objective
+// function, and the points in the returned Result and error are mapped to
+// it as well.
+func (toUser userSpace) run(ctx context.Context, f ObjectiveE, z0 []float64, parallelVertices int, options Options) (Result, error) {
+	internalF := func(z []float64) (float64, error) {
+		// A finite internal point can map to an infinite one, such as a
+		// large z squared by TransformSine on a half-bounded dimension.
+		x := toUser(z)
+		if !isFinite(x) {
+			return 0, errInfeasible
+		}
+		return f(x)
+	}
+	if observer := options.Observer; observer != nil {
+		options.Observer = func(info IterationInfo) bool {
+			info.Best = toUser.point(info.Best)
+			return observer(info)
+		}
+	}
+	result, err := runE(ctx, internalF, z0, parallelVertices, options)
+	result = toUser.result(result)
+	return result, toUser.error(err, result)
+}
+
+func (toUser userSpace) point(p Point) Point {
+	return Point{X: toUser(p.X), F: p.F}
+}
+
+// result maps the points of a result from the internal space.
+func (toUser userSpace) result(result Result) Result {
+	if len(result.Simplex.Points) == 0 {
+		return result
+	}
+	points := make([]Point, len(result.Simplex.Points))
+	for i, p := range result.Simplex.Points {
+		points[i] = toUser.point(p)
+	}
+	result.Simplex = Simplex{Points: points}
+	result.Point = points[0]
+	return result
+}
+
+// error maps the points carried by an error from the internal space.
+func (toUser userSpace) error(err error, result Result) error {
+	switch e := err.(type) {
+	case ErrorObjective:
+		e.X = toUser(e.X)
+		return e
+	case ErrorNonFiniteObjective:
+		e.X = toUser(e.X)
+		return e
+	case ErrorSimplexCollapse:
+		e.Point, e.Simplex = result.Point, result.Simplex
+		return e
+	}
+	return err
+}
+
+// reducedSpace is the search space of the dimensions that are not fixed by a
+// Constraint with Min equal to Max.
+type reducedSpace struct {
+	// fixed is a point with the value of every fixed dimension.
+	fixed []float64
+
+	// free has the index of each dimension that is not fixed.
+	free []int
+}
+
+// newReducedSpace returns the search space without the fixed dimensions and
+// whether any dimension is fixed.
+func newReducedSpace(x0 []float64, constraints []Constraint) (reducedSpace, bool) {
+	s := reducedSpace{fixed: slices.Clone(x0)}
+	for i, c := range constraints {
+		if c.Min == c.Max {
+			s.fixed[i] = c.Min
+		} else {
+			s.free = append(s.free, i)
+		}
+	}
+	return s, len(s.free) < len(constraints)
+}
+
+func (s reducedSpace) userSpace() userSpace {
+	return func(z []float64) []float64 {
+		x := slices.Clone(s.fixed)
+		for k, i := range s.free {
+			x[i] = z[k]
+		}
+		return x
+	}
+}
+
+// internal returns the starting point and options of the optimization of the
+// free dimensions.
+func (s reducedSpace) internal(x0 []float64, options Options) ([]float64, Options) {
+	z0 := make([]float64, len(s.free))
+	constraints := make([]Constraint, len(s.free))
+	for k, i := range s.free {
+		z0[k] = x0[i]
+		constraints[k] = options.Constraints[i]
+	}
+	options.Constraints = constraints
+	if initialSimplex, ok := options.InitialSimplex.(interface {
+		reduce(n int, free []int) InitialSimplex
+	}); ok {
+		options.InitialSimplex = initialSimplex.reduce(len(x0), s.free)
+	}
+	return z0, options
+}
@@ -0,0 +1,144 @@
+package neldermead
+
+import "math"
+
+// Transform selects a change of variables that maps an unconstrained internal search space onto the
+// Options.Constraints box, as done by MATLAB's fminsearchbnd. The optimization then moves freely in the
+// internal space and every point it visits maps to a point inside the bounds, so no point is ever clamped.
+type Transform int
+
+const (
+	// TransformNone optimizes in the same space as the objective function and moves points that leave the
+	// bounds back inside as configured by Options.BoundaryHandling.
+	TransformNone Transform = iota
+
+	// TransformSine maps each internal coordinate z to min + (max-min)*(sin(z)+1)/2. The mapping is periodic
+	// so a point can move past a bound and come back. The bounds themselves can be reached. A dimension that
+	// is only bounded below is mapped with min + z*z and one that is only bounded above with max - z*z.
+	TransformSine
+
+	// TransformLogistic maps each internal coordinate z to min + (max-min)/(1+exp(-z)), which is the same
+	// curve as the tanh transform with z scaled by 2. The bounds are only approached asymptotically. A
+	// dimension that is only bounded below is mapped with min + exp(z) and one that is only bounded above
+	// with max - exp(z). A starting point on a bound is moved to z = ±3, about 5% of the range inside a
+	// bounded dimension and exp(-3) away from the bound of a half-bounded one, where the curve is still
+	// steep enough for the initial simplex to move away from the bound.
+	TransformLogistic
+)
+
+// logisticLimit is the magnitude of the internal coordinate of
+// TransformLogistic for points on a bound, where the inverse of the mapping
+// is infinite. Further out the mapping is so flat that a simplex with unit
+// steps maps every vertex to the same point and the optimization stops
+// where it started.
+const logisticLimit = 3
+
+// searchSpace maps points between the space of the objective function and
+// the internal space the optimizer works in when Options.Transform is set.
+type searchSpace struct {
+	transform   Transform
+	constraints []Constraint
+}
+
+// toUser sets x to the point in the space of the objective function for
+// internal point z. Dimensions that are not bounded at all are not mapped.
+func (s searchSpace) toUser(x, z []float64) {
+	for i, c := range s.constraints {
+		var (
+			lower = !math.IsInf(c.Min, 0)
+			upper = !math.IsInf(c.Max, 0)
+		)
+		switch {
+		case lower && upper:
+			var u float64
+			switch s.transform {
+			case TransformSine:
+				u = (math.Sin(z[i]) + 1) / 2
+			case TransformLogistic:
+				u = 1 / (1 + math.Exp(-z[i]))
+			}
+			x[i] = c.Min + u*(c.Max-c.Min)
+		case lower:
+			x[i] = c.Min + s.halfBounded(z[i])
+		case upper:
+			x[i] = c.Max - s.halfBounded(z[i])
+		default:
+			x[i] = z[i]
+		}
+		x[i] = min(max(x[i], c.Min), c.Max)
+	}
+}
+
+// halfBounded maps an internal coordinate to a non-negative distance from
+// the bound of a dimension that is only bounded on one side.
+func (s searchSpace) halfBounded(z float64) float64 {
+	if s.transform == TransformSine {
+		return z * z
+	}
+	return math.Exp(z)
+}
+
+// toInternal sets z to the internal point for x, which must be inside the
+// constraints.
+func (s searchSpace) toInternal(z, x []float64) {
+	for i, c := range s.constraints {
+		var (
+			lower = !math.IsInf(c.Min, 0)
+			upper = !math.IsInf(c.Max, 0)
+		)
+		switch {
+		case lower && upper:
+			u := (x[i] - c.Min) / (c.Max - c.Min)
+			switch s.transform {
+			case TransformSine:
+				z[i] = math.Asin(min(max(2*u-1, -1), 1))
+			case TransformLogistic:
+				switch {
+				case u <= 0:
+					z[i] = -logisticLimit
+				case u >= 1:
+					z[i] = logisticLimit
+				default:
+					z[i] = math.Log(u / (1 - u))
+				}
+			}
+		case lower:
+			z[i] = s.halfBoundedInverse(x[i] - c.Min)
+		case upper:
+			z[i] = s.halfBoundedInverse(c.Max - x[i])
+		default:
+			z[i] = x[i]
+		}
+	}
+}
+
+// halfBoundedInverse is the inverse of halfBounded. A point on the bound
+// is placed at -logisticLimit for TransformLogistic.
+func (s searchSpace) halfBoundedInverse(d float64) float64 {
+	if s.transform == TransformSine {
+		return math.Sqrt(d)
+	}
+	if d <= 0 {
+		return -logisticLimit
+	}
+	return math.Log(d)
+}
+
+// userSpace returns the mapping from the internal space to the space of
+// the objective function.
+func (s searchSpace) userSpace() userSpace {
+	return func(z []float64) []float64 {
+		x := make([]float64, len(z))
+		s.toUser(x, z)
+		return x
+	}
+}
+
+// internal returns the starting point and options of the optimization in
+// the internal space.
+func (s searchSpace) internal(x0 []float64, options Options) ([]float64, Options) {
+	z0 := make([]float64, len(x0))
+	s.toInternal(z0, x0)
+	options.Constraints = nil
+	return z0, options
+}
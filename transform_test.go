@@ -0,0 +1,181 @@
+package neldermead
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestSearchSpace(t *testing.T) {
+	constraints := []Constraint{
+		{Min: -1, Max: 2},
+		{Min: 0, Max: 1e-3},
+		{Min: 10, Max: 1e6},
+		{Min: -5, Max: math.Inf(1)},
+		{Min: math.Inf(-1), Max: 5},
+		{Min: math.Inf(-1), Max: math.Inf(1)},
+	}
+	for _, tt := range []struct {
+		name      string
+		transform Transform
+	}{
+		{name: "sine", transform: TransformSine},
+		{name: "logistic", transform: TransformLogistic},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			space := searchSpace{transform: tt.transform, constraints: constraints}
+			for _, x := range [][]float64{
+				{0, 5e-4, 500, 0, 0, 0},
+				{-0.99, 1e-6, 999_000, 1e6, -1e6, 1e6},
+				{-1, 0, 10, -5, 5, -3},
+				{2, 1e-3, 1e6, -4.5, 4.5, 1e-300},
+			} {
+				z := make([]float64, len(x))
+				space.toInternal(z, x)
+				got := make([]float64, len(x))
+				space.toUser(got, z)
+				for i, c := range constraints {
+					// The logistic transform never reaches a bound, so a
+					// point on one maps back inside.
+					if onBound := x[i] == c.Min || x[i] == c.Max; onBound && tt.transform == TransformLogistic {
+						if !(got[i] > c.Min && got[i] < c.Max) {
+							t.Errorf("expected dimension %d of %v to map back inside the bounds got %v", i, x, got)
+						}
+						continue
+					}
+					if tolerance := 1e-9 * min(c.Max-c.Min, max(1, math.Abs(x[i]))); math.Abs(got[i]-x[i]) > tolerance {
+						t.Errorf("expected %v to map back to itself got %v", x, got)
+						break
+					}
+				}
+			}
+
+			// Every internal point maps inside the bounds.
+			x := make([]float64, len(constraints))
+			for _, zi := range []float64{-1e300, -100, -3, 0, 1.5, 7, 100, 1e300} {
+				space.toUser(x, []float64{zi, zi, zi, zi, zi, zi})
+				requireXToBeWithinConstraints(t, x, constraints)
+			}
+		})
+	}
+}
+
+func TestOptions_Transform(t *testing.T) {
+	constraints := []Constraint{{Min: 0, Max: 1}, {Min: 1, Max: 4}}
+
+	for _, tt := range []struct {
+		name      string
+		transform Transform
+		decimals  int
+	}{
+		{name: "sine", transform: TransformSine, decimals: 4},
+		// The logistic transform only approaches the bounds asymptotically.
+		{name: "logistic", transform: TransformLogistic, decimals: 2},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Run("interior minimum", func(t *testing.T) {
+				objective := feasibleObjective(t, constraints, func(x []float64) float64 {
+					return math.Pow(x[0]-0.3, 2) + math.Pow(x[1]-2, 2)
+				})
+				options := NewOptions()
+				options.Tolerance = 1e-12
+				options.Constraints = constraints
+				options.Transform = tt.transform
+
+				point, err := Run(objective, []float64{0.8, 3.5}, options)
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				expectPoint(t, Point{X: []float64{0.3, 2}, F: 0}, point, 4)
+			})
+
+			t.Run("x0 on the bounds", func(t *testing.T) {
+				objective := feasibleObjective(t, constraints, func(x []float64) float64 {
+					return math.Pow(x[0]-0.3, 2) + math.Pow(x[1]-2, 2)
+				})
+				options := NewOptions()
+				options.Tolerance = 1e-12
+				options.Constraints = constraints
+				options.Transform = tt.transform
+
+				for _, x0 := range [][]float64{{1, 1}, {0, 4}} {
+					result, err := RunWithResult(objective, x0, options)
+					if err != nil {
+						t.Fatalf("unexpected error: %v", err)
+					}
+					if result.Iterations == 0 {
+						t.Errorf("x0 = %v: expected the optimization to move away from the bounds", x0)
+					}
+					expectPoint(t, Point{X: []float64{0.3, 2}, F: 0}, result.Point, 4)
+				}
+			})
+
+			t.Run("x0 on the bound of a half-bounded dimension", func(t *testing.T) {
+				constraints := []Constraint{{Min: 0, Max: math.Inf(1)}, {Min: 0, Max: 1}}
+				objective := feasibleObjective(t, constraints, func(x []float64) float64 {
+					return math.Pow(x[0]-0.5, 2) + math.Pow(x[1]-0.5, 2)
+				})
+				options := NewOptions()
+				options.Tolerance = 1e-12
+				options.Constraints = constraints
+				options.Transform = tt.transform
+
+				point, err := Run(objective, []float64{0, 0.2}, options)
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				expectPoint(t, Point{X: []float64{0.5, 0.5}, F: 0}, point, 4)
+			})
+
+			t.Run("minimum on the bounds", func(t *testing.T) {
+				objective := feasibleObjective(t, constraints, func(x []float64) float64 {
+					return x[0] - x[1]
+				})
+				options := NewOptions()
+				options.Tolerance = 1e-12
+				options.Constraints = constraints
+				options.Transform = tt.transform
+				var observed []Point
+				options.Observer = func(info IterationInfo) bool {
+					observed = append(observed, info.Best)
+					return false
+				}
+
+				result, err := RunWithResult(objective, []float64{0.5, 2}, options)
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				expectPoint(t, Point{X: []float64{0, 4}, F: -4}, result.Point, tt.decimals)
+				for _, p := range append(observed, result.Simplex.Points...) {
+					requireXToBeWithinConstraints(t, p.X, constraints)
+					if f := objective(p.X); f != p.F {
+						t.Errorf("expected the point %v to be reported in the space of the objective function", p)
+					}
+				}
+			})
+		})
+	}
+
+	t.Run("errors report points in the space of the objective function", func(t *testing.T) {
+		errFailed := errors.New("failed")
+		objective := func(x []float64) (float64, error) {
+			if x[0] > 0.75 {
+				return 0, errFailed
+			}
+			return -x[0], nil
+		}
+		options := NewOptions()
+		options.Constraints = constraints
+		options.Transform = TransformSine
+
+		_, err := RunE(context.Background(), objective, []float64{0.5, 2}, options)
+		var objectiveErr ErrorObjective
+		if !errors.As(err, &objectiveErr) {
+			t.Fatalf("expected an ErrorObjective got %v", err)
+		}
+		if objectiveErr.X[0] <= 0.75 || objectiveErr.X[0] > 1 {
+			t.Errorf("expected the failing point to be reported in the space of the objective function got %v", objectiveErr.X)
+		}
+	})
+}